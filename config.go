@@ -0,0 +1,184 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the YAML configuration file read with -config. Each key is the
+// name of the command line flag it sets. Unset (nil) values leave the flag
+// default unchanged.
+type Config struct {
+	Dir             stringList `yaml:"dir"` // A single path or a list of paths.
+	Pkg             []string   `yaml:"pkg"`
+	HTTP            *string    `yaml:"http"`
+	UnixSocket      *string    `yaml:"unix-socket"`
+	APIKey          *string    `yaml:"api-key"`
+	CORSOrigins     *string    `yaml:"cors-origins"`
+	TLSCert         *string    `yaml:"tls-cert"`
+	TLSKey          *string    `yaml:"tls-key"`
+	OTelEndpoint    *string    `yaml:"otel-endpoint"`
+	NoLog           *bool      `yaml:"no-log"`
+	LogLevel        *string    `yaml:"log-level"`
+	LogFormat       *string    `yaml:"log-format"`
+	LogFile         *string    `yaml:"log-file"`
+	AuditLog        *string    `yaml:"audit-log"`
+	SlowQueryMS     *int       `yaml:"slow-query-ms"`
+	DBPath          *string    `yaml:"db-path"`
+	DBMaxConns      *int       `yaml:"db-max-conns"`
+	DBWorkers       *int       `yaml:"db-workers"`
+	InMemory        *bool      `yaml:"in-memory"`
+	Watch           *bool      `yaml:"watch"`
+	WaitForInit     *bool      `yaml:"wait-for-init"`
+	ContinueOnError *bool      `yaml:"continue-on-error"`
+	Strict          *bool      `yaml:"strict"`
+	PackageFilter   *string    `yaml:"package-filter"`
+	SkipPackage     []string   `yaml:"skip-package"`
+	MaxRows         *int       `yaml:"max-rows"`
+	MaxResultBytes  *int       `yaml:"max-result-bytes"`
+	MaxQueryTime    *string    `yaml:"max-query-time"` // Go duration (e.g. 30s).
+}
+
+// stringList is a list of strings that may also be written in YAML as a
+// single scalar value.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = stringList{node.Value}
+		return nil
+	}
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+// readConfig parses a YAML configuration file. Unknown keys are an error.
+func readConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+
+	var c Config
+	if err = dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// apply sets the flags of fs from the configuration. Flags that were set on
+// the command line take precedence and are left unchanged.
+func (c *Config) apply(fs *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	v := reflect.ValueOf(c).Elem()
+	for i := range v.NumField() {
+		name := v.Type().Field(i).Tag.Get("yaml")
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("config key %q has no matching flag", name)
+		}
+		if explicit[name] {
+			continue
+		}
+
+		var values []string
+		switch field := v.Field(i); field.Kind() {
+		case reflect.Pointer:
+			if field.IsNil() {
+				continue
+			}
+			values = []string{fmt.Sprint(field.Elem().Interface())}
+		case reflect.Slice:
+			values = field.Convert(reflect.TypeFor[[]string]()).Interface().([]string)
+		}
+		for _, value := range values {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("invalid config value for %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadConfigFile reads the configuration file at path and applies it to the
+// flags of fs that were not set on the command line.
+func loadConfigFile(fs *flag.FlagSet, path string) error {
+	c, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+	return c.apply(fs)
+}
+
+// envPrefix is the prefix of environment variables that set flags. For
+// example, FLEETPKG_LOG_LEVEL sets -log-level.
+const envPrefix = "FLEETPKG_"
+
+// envVarName returns the environment variable that sets the named flag.
+func envVarName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnv sets the flags of fs that were not set on the command line from
+// their FLEETPKG_* environment variables. Repeatable flags accept a
+// comma-separated list.
+func applyEnv(fs *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envVarName(f.Name))
+		if !ok || explicit[f.Name] || err != nil {
+			return
+		}
+
+		values := []string{value}
+		if _, repeatable := f.Value.(*stringsFlag); repeatable {
+			values = strings.Split(value, ",")
+		}
+		for _, v := range values {
+			if setErr := fs.Set(f.Name, strings.TrimSpace(v)); setErr != nil {
+				err = fmt.Errorf("invalid value for %s: %w", envVarName(f.Name), setErr)
+				return
+			}
+		}
+	})
+	return err
+}
+
+// setupFlags parses the command line. Flags that are not given on the command
+// line are then set from FLEETPKG_* environment variables and finally from
+// the -config file, so the precedence is command line, environment, config
+// file, and then the flag default.
+func setupFlags() error {
+	flag.Parse()
+
+	if err := applyEnv(flag.CommandLine); err != nil {
+		return err
+	}
+	if *configPath != "" {
+		return loadConfigFile(flag.CommandLine, *configPath)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fleetpkg-mcp.yml")
+	err := os.WriteFile(path, []byte(`
+dir: /src/integrations
+log-level: debug
+max-rows: 100
+max-query-time: 5s
+in-memory: true
+skip-package:
+  - nginx
+  - aws
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	dir := fs.String("dir", "", "")
+	level := fs.String("log-level", "info", "")
+	rows := fs.Int("max-rows", 10000, "")
+	queryTime := fs.Duration("max-query-time", 30*time.Second, "")
+	memory := fs.Bool("in-memory", false, "")
+	var skip stringsFlag
+	fs.Var(&skip, "skip-package", "")
+	// Register the remaining flags that Config can set.
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		if fs.Lookup(f.Name) == nil {
+			fs.Var(&stringsFlag{}, f.Name, f.Usage)
+		}
+	})
+
+	// Command line flags take precedence over the config file.
+	if err = fs.Parse([]string{"-max-rows", "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = loadConfigFile(fs, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if *dir != "/src/integrations" {
+		t.Errorf("expected dir from config, got %q", *dir)
+	}
+	if *level != "debug" {
+		t.Errorf("expected log-level debug, got %q", *level)
+	}
+	if *rows != 5 {
+		t.Errorf("expected max-rows from the command line (5), got %d", *rows)
+	}
+	if *queryTime != 5*time.Second {
+		t.Errorf("expected max-query-time 5s, got %v", *queryTime)
+	}
+	if !*memory {
+		t.Error("expected in-memory to be true")
+	}
+	if !slices.Equal(skip, []string{"nginx", "aws"}) {
+		t.Errorf("unexpected skip-package %v", skip)
+	}
+}
+
+func TestLoadConfigFileUnknownKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fleetpkg-mcp.yml")
+	if err := os.WriteFile(path, []byte("log-levle: debug\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readConfig(path); err == nil {
+		t.Fatal("expected an error for an unknown config key")
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	defer func(level string) { *logLevel = level }(*logLevel)
+	t.Setenv("FLEETPKG_LOG_LEVEL", "debug")
+
+	if err := applyEnv(flag.CommandLine); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	log, err := logger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !log.Enabled(t.Context(), slog.LevelDebug) {
+		t.Fatalf("expected debug logging to be enabled, log-level is %q", *logLevel)
+	}
+}
+
+func TestApplyEnvCommandLinePrecedence(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	level := fs.String("log-level", "info", "")
+	var skip stringsFlag
+	fs.Var(&skip, "skip-package", "")
+	if err := fs.Parse([]string{"-log-level", "warn"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FLEETPKG_LOG_LEVEL", "debug")
+	t.Setenv("FLEETPKG_SKIP_PACKAGE", "nginx,aws")
+
+	if err := applyEnv(fs); err != nil {
+		t.Fatal(err)
+	}
+	if *level != "warn" {
+		t.Errorf("expected log-level from the command line (warn), got %q", *level)
+	}
+	if !slices.Equal(skip, []string{"nginx", "aws"}) {
+		t.Errorf("unexpected skip-package %v", skip)
+	}
+}
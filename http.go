@@ -0,0 +1,160 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gorilla/handlers"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/andrewkroh/fleetpkg-mcp/internal/metrics"
+)
+
+// newHTTPHandler returns the handler for the HTTP transport. It serves the
+// MCP streamable HTTP protocol, a /health endpoint for readiness probes, and
+// Prometheus metrics at /metrics.
+func newHTTPHandler(s *mcp.Server, db *atomic.Pointer[sql.DB]) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/health", healthHandler(db))
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/", mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server { return s }, nil))
+	return mux
+}
+
+// httpMiddleware wraps the handler of the HTTP transport with API key
+// authentication when apiKey is set, CORS when corsOrigins (a comma-separated
+// list) is set, and gzip compression. CORS is applied outside of the API key
+// check so that preflight requests, which carry no credentials, succeed.
+func httpMiddleware(handler http.Handler, apiKey, corsOrigins string) http.Handler {
+	if apiKey != "" {
+		handler = apiKeyMiddleware(apiKey, handler)
+	}
+	if corsOrigins != "" {
+		handler = corsMiddleware(strings.Split(corsOrigins, ","), handler)
+	}
+	// Large JSON query results compress well.
+	return handlers.CompressHandler(handler)
+}
+
+// healthHandler reports 200 with {"status":"ready"} once the database is
+// loaded and 503 with {"status":"initializing"} before that.
+func healthHandler(db *atomic.Pointer[sql.DB]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, code := "ready", http.StatusOK
+		if db.Load() == nil {
+			status, code = "initializing", http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": status})
+	})
+}
+
+// apiKeyMiddleware rejects requests that do not carry the given key in an
+// "Authorization: Bearer <key>" header with 401 Unauthorized.
+func apiKeyMiddleware(key string, next http.Handler) http.Handler {
+	want := []byte(key)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsMiddleware adds CORS headers to responses for requests from one of the
+// allowed origins and answers OPTIONS preflight requests with 204. An origin
+// of "*" allows all origins.
+func corsMiddleware(origins []string, next http.Handler) http.Handler {
+	for i := range origins {
+		origins[i] = strings.TrimSpace(origins[i])
+	}
+	allowAll := slices.Contains(origins, "*")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && (allowAll || slices.Contains(origins, origin)) {
+			h := w.Header()
+			if allowAll {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Add("Vary", "Origin")
+			}
+			h.Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			h.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Last-Event-ID, Mcp-Protocol-Version, Mcp-Session-Id")
+			h.Set("Access-Control-Expose-Headers", "Mcp-Session-Id")
+		}
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// listenHTTP returns the listener for the HTTP transport. If socketPath is set
+// then it listens on a Unix domain socket at that path, which is removed when
+// the listener is closed. Otherwise it listens on the TCP address addr.
+func listenHTTP(addr, socketPath string) (net.Listener, error) {
+	if socketPath != "" {
+		listener, err := net.Listen("unix", socketPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on unix socket %q: %w", socketPath, err)
+		}
+		return listener, nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %q: %w", addr, err)
+	}
+	return listener, nil
+}
+
+// listenWhenReady returns the listener for the HTTP transport like listenHTTP.
+// If wait is set then it does not listen until the database initialization
+// that reports to initErrCh has finished, so no connections are accepted
+// before the database is ready.
+func listenWhenReady(ctx context.Context, initErrCh <-chan error, wait bool, addr, socketPath string) (net.Listener, error) {
+	if wait {
+		if err := waitForInit(ctx, initErrCh); err != nil {
+			return nil, err
+		}
+	}
+	return listenHTTP(addr, socketPath)
+}
+
+// serveHTTP serves handler on the listener. If certFile and keyFile are set
+// then the connections use TLS.
+func serveHTTP(listener net.Listener, handler http.Handler, certFile, keyFile string) error {
+	if certFile == "" && keyFile == "" {
+		return http.Serve(listener, handler)
+	}
+
+	srv := &http.Server{
+		Handler: handler,
+		TLSConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		},
+	}
+	return srv.ServeTLS(listener, certFile, keyFile)
+}
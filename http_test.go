@@ -0,0 +1,474 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestHealthEndpoint(t *testing.T) {
+	dbPtr := &atomic.Pointer[sql.DB]{}
+	s := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	srv := httptest.NewServer(newHTTPHandler(s, dbPtr))
+	defer srv.Close()
+
+	// Before initialization the endpoint reports 503.
+	resp, err := http.Get(srv.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 before initialization, got %d", resp.StatusCode)
+	}
+
+	// Initialize in the background like run does.
+	loader := &databaseLoader{
+		log:              slog.New(slog.DiscardHandler),
+		integrationsDirs: []string{fixtureDir},
+		opts:             dbOptions{path: filepath.Join(t.TempDir(), "fleetpkg.db")},
+		db:               dbPtr,
+	}
+	go func() {
+		db, err := loader.build(t.Context())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		dbPtr.Store(db)
+	}()
+	defer func() {
+		if db := dbPtr.Load(); db != nil {
+			db.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case <-timeout:
+			t.Fatal("timed out waiting for /health to report ready")
+		case <-ticker.C:
+		}
+
+		resp, err := http.Get(srv.URL + "/health")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return
+		}
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	dbPtr := &atomic.Pointer[sql.DB]{}
+	s := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	srv := httptest.NewServer(httpMiddleware(newHTTPHandler(s, dbPtr), "secret", "https://example.com"))
+	defer srv.Close()
+
+	do := func(method, auth string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+"/health", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Origin", "https://example.com")
+		// Setting the header disables the transparent decompression of the client.
+		req.Header.Set("Accept-Encoding", "gzip")
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	// Requests without the API key are rejected.
+	if resp := do(http.MethodGet, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without API key, got %d", resp.StatusCode)
+	}
+
+	// Preflight requests carry no credentials and are answered by CORS.
+	resp := do(http.MethodOptions, "")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 for preflight, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://example.com" {
+		t.Fatalf("expected CORS header on preflight, got %q", got)
+	}
+
+	// Authorized responses are compressed.
+	resp = do(http.MethodGet, "secret")
+	if got := resp.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected Content-Encoding gzip, got %q", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://example.com" {
+		t.Fatalf("expected CORS header, got %q", got)
+	}
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "initializing") {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestServeHTTPTLS(t *testing.T) {
+	certFile, keyFile, certPEM := writeSelfSignedCert(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	dbPtr := &atomic.Pointer[sql.DB]{}
+	s := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	serveDone := make(chan error, 1)
+	go func() {
+		serveDone <- serveHTTP(listener, newHTTPHandler(s, dbPtr), certFile, keyFile)
+	}()
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+
+	resp, err := client.Get("https://" + listener.Addr().String() + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.TLS == nil {
+		t.Fatal("expected a TLS connection")
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", resp.StatusCode)
+	}
+
+	listener.Close()
+	if err = <-serveDone; err != nil && !errors.Is(err, net.ErrClosed) {
+		t.Fatal(err)
+	}
+}
+
+func TestServeHTTPUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "fleetpkg.sock")
+	listener, err := listenHTTP("", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	dbPtr := &atomic.Pointer[sql.DB]{}
+	s := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	serveDone := make(chan error, 1)
+	go func() {
+		serveDone <- serveHTTP(listener, newHTTPHandler(s, dbPtr), "", "")
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://fleetpkg/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", resp.StatusCode)
+	}
+
+	listener.Close()
+	if err = <-serveDone; err != nil && !errors.Is(err, net.ErrClosed) {
+		t.Fatal(err)
+	}
+
+	// The socket file is removed on shutdown.
+	if _, err = os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Fatalf("expected socket file to be removed, got %v", err)
+	}
+}
+
+func TestListenWhenReady(t *testing.T) {
+	t.Run("listens after initialization", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "fleetpkg.sock")
+		initErrCh := make(chan error, 1)
+
+		type result struct {
+			listener net.Listener
+			err      error
+		}
+		done := make(chan result, 1)
+		go func() {
+			listener, err := listenWhenReady(t.Context(), initErrCh, true, "", socketPath)
+			done <- result{listener, err}
+		}()
+
+		select {
+		case r := <-done:
+			t.Fatalf("expected listenWhenReady to block during initialization, got %v", r.err)
+		default:
+		}
+		if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+			t.Fatalf("expected no socket before initialization completes, got %v", err)
+		}
+
+		close(initErrCh)
+		r := <-done
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		defer r.listener.Close()
+
+		conn, err := net.Dial("unix", socketPath)
+		if err != nil {
+			t.Fatalf("expected connection to succeed after initialization: %v", err)
+		}
+		conn.Close()
+	})
+
+	t.Run("initialization error", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "fleetpkg.sock")
+		initErrCh := make(chan error, 1)
+		initErrCh <- errors.New("boom")
+
+		listener, err := listenWhenReady(t.Context(), initErrCh, true, "", socketPath)
+		if err == nil {
+			listener.Close()
+			t.Fatal("expected an initialization error")
+		}
+		if !strings.Contains(err.Error(), "boom") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err = os.Stat(socketPath); !os.IsNotExist(err) {
+			t.Fatalf("expected no socket after failed initialization, got %v", err)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "fleetpkg.sock")
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		if _, err := listenWhenReady(ctx, make(chan error), true, "", socketPath); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+			t.Fatalf("expected no socket when cancelled, got %v", err)
+		}
+	})
+
+	t.Run("no wait", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "fleetpkg.sock")
+
+		// The initialization channel is never closed.
+		listener, err := listenWhenReady(t.Context(), make(chan error), false, "", socketPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		listener.Close()
+	})
+}
+
+// writeSelfSignedCert writes a self-signed certificate for 127.0.0.1 and its
+// key to a temporary directory. It returns the file paths and the PEM encoded
+// certificate.
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string, certPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fleetpkg-mcp test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err = os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, certPEM
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := apiKeyMiddleware("secret", next)
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"correct token", "Bearer secret", http.StatusOK},
+		{"wrong token", "Bearer wrong", http.StatusUnauthorized},
+		{"missing header", "", http.StatusUnauthorized},
+		{"wrong scheme", "Basic secret", http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := corsMiddleware([]string{"https://a.example", " https://b.example"}, next)
+
+	t.Run("preflight", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", "https://b.example")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://b.example" {
+			t.Errorf("unexpected Access-Control-Allow-Origin %q", got)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, http.MethodPost) {
+			t.Errorf("unexpected Access-Control-Allow-Methods %q", got)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(got, "Authorization") {
+			t.Errorf("unexpected Access-Control-Allow-Headers %q", got)
+		}
+	})
+
+	t.Run("disallowed origin", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Origin", "https://evil.example")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("unexpected Access-Control-Allow-Origin %q", got)
+		}
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Origin", "https://any.example")
+		rec := httptest.NewRecorder()
+		corsMiddleware([]string{"*"}, next).ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("unexpected Access-Control-Allow-Origin %q", got)
+		}
+	})
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	s := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	srv := httptest.NewServer(newHTTPHandler(s, &atomic.Pointer[sql.DB]{}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	for _, name := range []string{
+		"fleetpkg_queries_total",
+		"fleetpkg_query_duration_seconds",
+		"fleetpkg_db_ready",
+	} {
+		if !strings.Contains(string(body), name) {
+			t.Errorf("metric %q not found in response", name)
+		}
+	}
+}
@@ -22,12 +22,13 @@ type gentablesFlags struct {
 var flags gentablesFlags
 
 func init() {
-	flag.StringVar(&flags.schema, "schema", "", "sql file containing 'CREATE TABLE' statements")
+	flag.StringVar(&flags.schema, "schema", "", "sql file containing 'CREATE TABLE' and 'CREATE INDEX' statements")
 	flag.StringVar(&flags.out, "out", "", "output file name")
 }
 
 var (
-	createTableRegex = regexp.MustCompile(`(?m)(?:-- .*\n)*CREATE TABLE (?:IF NOT EXISTS )?([\w_]+)[^;]+;`)
+	createTableRegex = regexp.MustCompile(`(?m)(?:-- .*\n)*CREATE (?:VIRTUAL )?TABLE (?:IF NOT EXISTS )?([\w_]+)[^;]+;`)
+	createIndexRegex = regexp.MustCompile(`(?m)^CREATE INDEX (?:IF NOT EXISTS )?[\w_]+ ON [^;]+;`)
 
 	funcMap = template.FuncMap{
 		"backquote":      backquote,
@@ -50,6 +51,18 @@ var Creates = [...]string{
 	{{ lowerSnakeCase $t.Name }}TableStatement,
 {{- end }}
 }
+
+var TableNames = [...]string{
+{{- range $t := .tables }}
+	"{{ $t.Name }}",
+{{- end }}
+}
+
+var Indexes = [...]string{
+{{- range $i := .indexes }}
+	{{ backquote $i }},
+{{- end }}
+}
 `[1:]))
 )
 
@@ -78,13 +91,18 @@ func Main() error {
 		tables = append(tables, Table{string(match[1]), string(match[0])})
 	}
 
+	var indexes []string
+	for _, match := range createIndexRegex.FindAll(data, -1) {
+		indexes = append(indexes, string(match))
+	}
+
 	f, err := os.Create(flags.out)
 	if err != nil {
 		return fmt.Errorf("failed creating output file: %w", err)
 	}
 	defer f.Close()
 
-	return tmpl.Execute(f, map[string]any{"tables": tables})
+	return tmpl.Execute(f, map[string]any{"tables": tables, "indexes": indexes})
 }
 
 func backquote(s string) (string, error) {
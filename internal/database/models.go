@@ -71,6 +71,20 @@ type DiscoveryField struct {
 	Name          sql.NullString
 }
 
+type DissectPattern struct {
+	ID                int64
+	IntegrationID     int64
+	IngestProcessorID int64
+	PipelineName      sql.NullString
+	Field             sql.NullString
+	Tokenizer         string
+}
+
+type EcsVersionRef struct {
+	IntegrationID int64
+	EcsReference  string
+}
+
 type Field struct {
 	ID              int64
 	Name            string
@@ -105,14 +119,25 @@ type Field struct {
 	Col             int64
 }
 
+type GrokPattern struct {
+	ID                int64
+	IntegrationID     int64
+	IngestProcessorID int64
+	PipelineName      sql.NullString
+	Field             sql.NullString
+	Pattern           string
+	DefinitionName    sql.NullString
+}
+
 type IngestPipeline struct {
-	ID           int64
-	DataStreamID int64
-	Name         sql.NullString
-	Description  sql.NullString
-	Version      sql.NullInt64
-	Meta         sql.NullString
-	FilePath     string
+	ID             int64
+	DataStreamID   int64
+	Name           sql.NullString
+	Description    sql.NullString
+	Version        sql.NullInt64
+	Meta           sql.NullString
+	FilePath       string
+	ProcessorCount sql.NullInt64
 }
 
 type IngestProcessor struct {
@@ -121,6 +146,7 @@ type IngestProcessor struct {
 	Type             string
 	Attributes       interface{}
 	JsonPointer      string
+	Depth            int64
 	FilePath         string
 	LineNumber       int64
 	Col              int64
@@ -161,6 +187,17 @@ type IntegrationIcon struct {
 	Size          sql.NullString
 	Type          sql.NullString
 	DarkMode      sql.NullBool
+	IconWidth     sql.NullInt64
+	IconHeight    sql.NullInt64
+	IconByteSize  sql.NullInt64
+}
+
+type IntegrationReadme struct {
+	ID            int64
+	IntegrationID int64
+	Content       string
+	Truncated     bool
+	FilePath      string
 }
 
 type IntegrationScreenshot struct {
@@ -175,11 +212,34 @@ type IntegrationScreenshot struct {
 	Type          sql.NullString
 }
 
+type IntegrationStat struct {
+	IntegrationID         int64
+	TotalDataStreams      int64
+	TotalFields           int64
+	TotalPipelines        int64
+	TotalProcessors       int64
+	TotalTransforms       int64
+	TotalChangelogEntries int64
+}
+
 type IntegrationVar struct {
 	IntegrationID int64
 	VarID         int64
 }
 
+type PackageDependency struct {
+	IntegrationID          int64
+	DependencyName         string
+	DependencyVersionRange string
+}
+
+type PipelineReference struct {
+	ID                     int64
+	SourcePipelineID       int64
+	IngestProcessorID      int64
+	ReferencedPipelineName string
+}
+
 type PolicyTemplate struct {
 	ID                                              int64
 	IntegrationID                                   int64
@@ -219,6 +279,9 @@ type PolicyTemplateIcon struct {
 	Size             sql.NullString
 	Type             sql.NullString
 	DarkMode         sql.NullBool
+	IconWidth        sql.NullInt64
+	IconHeight       sql.NullInt64
+	IconByteSize     sql.NullInt64
 }
 
 type PolicyTemplateInput struct {
@@ -254,6 +317,13 @@ type PolicyTemplateVar struct {
 	VarID            int64
 }
 
+type ProcessorFieldWrite struct {
+	ID                int64
+	IngestProcessorID int64
+	Operation         string
+	FieldName         string
+}
+
 type Release struct {
 	ID          int64
 	ChangelogID int64
@@ -270,6 +340,13 @@ type SampleEvent struct {
 	FilePath     string
 }
 
+type SampleEventField struct {
+	ID            int64
+	SampleEventID int64
+	DataStreamID  int64
+	FieldName     string
+}
+
 type Stream struct {
 	ID           int64
 	DataStreamID int64
@@ -300,6 +377,7 @@ type Transform struct {
 	TransformPivotAggs                       sql.NullString
 	TransformLatestSort                      sql.NullString
 	TransformLatestUniqueKey                 sql.NullString
+	TransformType                            sql.NullString
 	TransformDescription                     sql.NullString
 	TransformFrequency                       sql.NullString
 	TransformSettingsDatesAsEpochMillis      sql.NullBool
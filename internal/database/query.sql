@@ -128,10 +128,18 @@ INSERT INTO transforms (integration_id, name, transform_source_index,
 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
         ?, ?, ?, ?, ?, ?) RETURNING id;
 
+-- name: InsertIntegrationReadme :exec
+INSERT INTO integration_readme (integration_id, content, truncated, file_path)
+VALUES (?, ?, ?, ?);
+
 -- name: InsertIntegrationCategory :exec
 INSERT INTO integration_categories (integration_id, category)
 VALUES (?, ?);
 
+-- name: InsertPackageDependency :exec
+INSERT INTO package_dependencies (integration_id, dependency_name, dependency_version_range)
+VALUES (?, ?, ?);
+
 -- name: InsertPolicyTemplateCategory :exec
 INSERT INTO policy_template_categories (policy_template_id, category)
 VALUES (?, ?);
@@ -141,16 +149,18 @@ INSERT INTO policy_template_data_streams (policy_template_id, data_stream_name)
 VALUES (?, ?);
 
 -- name: InsertIntegrationIcon :one
-INSERT INTO integration_icons (integration_id, src, title, size, type, dark_mode)
-VALUES (?, ?, ?, ?, ?, ?) RETURNING id;
+INSERT INTO integration_icons (integration_id, src, title, size, type, dark_mode, icon_width, icon_height,
+                               icon_byte_size)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;
 
 -- name: InsertIntegrationScreenshot :one
 INSERT INTO integration_screenshots (integration_id, src, title, size, type, width, height, byte_size)
 VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;
 
 -- name: InsertPolicyTemplateIcon :one
-INSERT INTO policy_template_icons (policy_template_id, src, title, size, type, dark_mode)
-VALUES (?, ?, ?, ?, ?, ?) RETURNING id;
+INSERT INTO policy_template_icons (policy_template_id, src, title, size, type, dark_mode, icon_width,
+                                   icon_height, icon_byte_size)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;
 
 -- name: InsertPolicyTemplateScreenshot :one
 INSERT INTO policy_template_screenshots (policy_template_id, src, title, size, type, width, height, byte_size)
@@ -176,13 +186,33 @@ VALUES (?, ?, ?, ?, ?, ?) RETURNING id;
 
 -- name: InsertIngestProcessor :one
 INSERT INTO ingest_processors (ingest_pipeline_id, type, attributes, json_pointer,
-                                file_path, line_number, col)
-VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id;
+                                depth, file_path, line_number, col)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;
+
+-- name: InsertGrokPattern :exec
+INSERT INTO grok_patterns (integration_id, ingest_processor_id, pipeline_name, field, pattern, definition_name)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: InsertDissectPattern :exec
+INSERT INTO dissect_patterns (integration_id, ingest_processor_id, pipeline_name, field, tokenizer)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: InsertProcessorFieldWrite :exec
+INSERT INTO processor_field_writes (ingest_processor_id, operation, field_name)
+VALUES (?, ?, ?);
+
+-- name: InsertPipelineReference :exec
+INSERT INTO pipeline_references (source_pipeline_id, ingest_processor_id, referenced_pipeline_name)
+VALUES (?, ?, ?);
 
 -- name: InsertSampleEvent :one
 INSERT INTO sample_events (data_stream_id, event, file_path)
 VALUES (?, ?, ?) RETURNING id;
 
+-- name: InsertSampleEventField :exec
+INSERT INTO sample_event_fields (sample_event_id, data_stream_id, field_name)
+VALUES (?, ?, ?);
+
 -- name: InsertChangelog :one
 INSERT INTO changelogs (integration_id, file_path)
 VALUES (?, ?) RETURNING id;
@@ -199,3 +229,72 @@ VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id;
 -- name: InsertTransformDestAlias :one
 INSERT INTO transform_dest_aliases (transform_id, alias, move_on_creation)
 VALUES (?, ?, ?) RETURNING id;
+
+-- name: ListDuplicateDataStreamFields :many
+SELECT fields.name, COUNT(DISTINCT data_stream_fields.fields_file_name) AS file_count
+FROM data_stream_fields
+         JOIN fields ON fields.id = data_stream_fields.field_id
+WHERE data_stream_fields.data_stream_id = ?
+GROUP BY fields.name
+HAVING file_count > 1
+ORDER BY fields.name;
+
+-- name: PopulateEcsVersionRefs :exec
+INSERT INTO ecs_version_refs (integration_id, ecs_reference)
+SELECT DISTINCT integration_id, dependencies_ecs_reference
+FROM build_manifests
+WHERE dependencies_ecs_reference IS NOT NULL
+  AND dependencies_ecs_reference != '';
+
+-- name: PopulateFieldsFTS :exec
+INSERT INTO fields_fts (rowid, name, description)
+SELECT id, name, description
+FROM fields;
+
+-- name: PopulateIntegrationStats :exec
+INSERT INTO integration_stats (integration_id, total_data_streams, total_fields, total_pipelines,
+                               total_processors, total_transforms, total_changelog_entries)
+SELECT integrations.id,
+       (SELECT COUNT(*)
+        FROM data_streams
+        WHERE data_streams.integration_id = integrations.id),
+       (SELECT COUNT(DISTINCT data_stream_fields.data_stream_id || ':' || fields.name)
+        FROM data_stream_fields
+                 JOIN fields ON fields.id = data_stream_fields.field_id
+                 JOIN data_streams ON data_streams.id = data_stream_fields.data_stream_id
+        WHERE data_streams.integration_id = integrations.id),
+       (SELECT COUNT(*)
+        FROM ingest_pipelines
+                 JOIN data_streams ON data_streams.id = ingest_pipelines.data_stream_id
+        WHERE data_streams.integration_id = integrations.id),
+       (SELECT COUNT(*)
+        FROM ingest_processors
+                 JOIN ingest_pipelines ON ingest_pipelines.id = ingest_processors.ingest_pipeline_id
+                 JOIN data_streams ON data_streams.id = ingest_pipelines.data_stream_id
+        WHERE data_streams.integration_id = integrations.id),
+       (SELECT COUNT(*)
+        FROM transforms
+        WHERE transforms.integration_id = integrations.id),
+       (SELECT COUNT(*)
+        FROM changes
+                 JOIN releases ON releases.id = changes.release_id
+                 JOIN changelogs ON changelogs.id = releases.changelog_id
+        WHERE changelogs.integration_id = integrations.id)
+FROM integrations;
+
+-- name: PopulateIntegrationsFTS :exec
+INSERT INTO integrations_fts (rowid, name, title, description)
+SELECT id, name, title, description
+FROM integrations;
+
+-- name: PopulateIntegrationReadmeFTS :exec
+INSERT INTO integration_readme_fts (rowid, content)
+SELECT id, content
+FROM integration_readme;
+
+-- name: UpdateIngestPipelineProcessorCount :exec
+UPDATE ingest_pipelines
+SET processor_count = (SELECT COUNT(*)
+                       FROM ingest_processors
+                       WHERE ingest_processors.ingest_pipeline_id = ingest_pipelines.id)
+WHERE id = ?;
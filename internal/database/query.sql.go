@@ -182,6 +182,30 @@ func (q *Queries) InsertDiscoveryField(ctx context.Context, arg InsertDiscoveryF
 	return id, err
 }
 
+const insertDissectPattern = `-- name: InsertDissectPattern :exec
+INSERT INTO dissect_patterns (integration_id, ingest_processor_id, pipeline_name, field, tokenizer)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type InsertDissectPatternParams struct {
+	IntegrationID     int64
+	IngestProcessorID int64
+	PipelineName      sql.NullString
+	Field             sql.NullString
+	Tokenizer         string
+}
+
+func (q *Queries) InsertDissectPattern(ctx context.Context, arg InsertDissectPatternParams) error {
+	_, err := q.db.ExecContext(ctx, insertDissectPattern,
+		arg.IntegrationID,
+		arg.IngestProcessorID,
+		arg.PipelineName,
+		arg.Field,
+		arg.Tokenizer,
+	)
+	return err
+}
+
 const insertField = `-- name: InsertField :one
 INSERT INTO fields (name, type, description, value, example, pattern,
                     date_format,
@@ -264,6 +288,32 @@ func (q *Queries) InsertField(ctx context.Context, arg InsertFieldParams) (int64
 	return id, err
 }
 
+const insertGrokPattern = `-- name: InsertGrokPattern :exec
+INSERT INTO grok_patterns (integration_id, ingest_processor_id, pipeline_name, field, pattern, definition_name)
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+type InsertGrokPatternParams struct {
+	IntegrationID     int64
+	IngestProcessorID int64
+	PipelineName      sql.NullString
+	Field             sql.NullString
+	Pattern           string
+	DefinitionName    sql.NullString
+}
+
+func (q *Queries) InsertGrokPattern(ctx context.Context, arg InsertGrokPatternParams) error {
+	_, err := q.db.ExecContext(ctx, insertGrokPattern,
+		arg.IntegrationID,
+		arg.IngestProcessorID,
+		arg.PipelineName,
+		arg.Field,
+		arg.Pattern,
+		arg.DefinitionName,
+	)
+	return err
+}
+
 const insertIngestPipeline = `-- name: InsertIngestPipeline :one
 INSERT INTO ingest_pipelines (data_stream_id, name, description, version, meta,
                                file_path)
@@ -295,8 +345,8 @@ func (q *Queries) InsertIngestPipeline(ctx context.Context, arg InsertIngestPipe
 
 const insertIngestProcessor = `-- name: InsertIngestProcessor :one
 INSERT INTO ingest_processors (ingest_pipeline_id, type, attributes, json_pointer,
-                                file_path, line_number, col)
-VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id
+                                depth, file_path, line_number, col)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id
 `
 
 type InsertIngestProcessorParams struct {
@@ -304,6 +354,7 @@ type InsertIngestProcessorParams struct {
 	Type             string
 	Attributes       interface{}
 	JsonPointer      string
+	Depth            int64
 	FilePath         string
 	LineNumber       int64
 	Col              int64
@@ -315,6 +366,7 @@ func (q *Queries) InsertIngestProcessor(ctx context.Context, arg InsertIngestPro
 		arg.Type,
 		arg.Attributes,
 		arg.JsonPointer,
+		arg.Depth,
 		arg.FilePath,
 		arg.LineNumber,
 		arg.Col,
@@ -401,8 +453,9 @@ func (q *Queries) InsertIntegrationCategory(ctx context.Context, arg InsertInteg
 }
 
 const insertIntegrationIcon = `-- name: InsertIntegrationIcon :one
-INSERT INTO integration_icons (integration_id, src, title, size, type, dark_mode)
-VALUES (?, ?, ?, ?, ?, ?) RETURNING id
+INSERT INTO integration_icons (integration_id, src, title, size, type, dark_mode, icon_width, icon_height,
+                               icon_byte_size)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id
 `
 
 type InsertIntegrationIconParams struct {
@@ -412,6 +465,9 @@ type InsertIntegrationIconParams struct {
 	Size          sql.NullString
 	Type          sql.NullString
 	DarkMode      sql.NullBool
+	IconWidth     sql.NullInt64
+	IconHeight    sql.NullInt64
+	IconByteSize  sql.NullInt64
 }
 
 func (q *Queries) InsertIntegrationIcon(ctx context.Context, arg InsertIntegrationIconParams) (int64, error) {
@@ -422,12 +478,37 @@ func (q *Queries) InsertIntegrationIcon(ctx context.Context, arg InsertIntegrati
 		arg.Size,
 		arg.Type,
 		arg.DarkMode,
+		arg.IconWidth,
+		arg.IconHeight,
+		arg.IconByteSize,
 	)
 	var id int64
 	err := row.Scan(&id)
 	return id, err
 }
 
+const insertIntegrationReadme = `-- name: InsertIntegrationReadme :exec
+INSERT INTO integration_readme (integration_id, content, truncated, file_path)
+VALUES (?, ?, ?, ?)
+`
+
+type InsertIntegrationReadmeParams struct {
+	IntegrationID int64
+	Content       string
+	Truncated     bool
+	FilePath      string
+}
+
+func (q *Queries) InsertIntegrationReadme(ctx context.Context, arg InsertIntegrationReadmeParams) error {
+	_, err := q.db.ExecContext(ctx, insertIntegrationReadme,
+		arg.IntegrationID,
+		arg.Content,
+		arg.Truncated,
+		arg.FilePath,
+	)
+	return err
+}
+
 const insertIntegrationScreenshot = `-- name: InsertIntegrationScreenshot :one
 INSERT INTO integration_screenshots (integration_id, src, title, size, type, width, height, byte_size)
 VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id
@@ -475,6 +556,38 @@ func (q *Queries) InsertIntegrationVar(ctx context.Context, arg InsertIntegratio
 	return err
 }
 
+const insertPackageDependency = `-- name: InsertPackageDependency :exec
+INSERT INTO package_dependencies (integration_id, dependency_name, dependency_version_range)
+VALUES (?, ?, ?)
+`
+
+type InsertPackageDependencyParams struct {
+	IntegrationID          int64
+	DependencyName         string
+	DependencyVersionRange string
+}
+
+func (q *Queries) InsertPackageDependency(ctx context.Context, arg InsertPackageDependencyParams) error {
+	_, err := q.db.ExecContext(ctx, insertPackageDependency, arg.IntegrationID, arg.DependencyName, arg.DependencyVersionRange)
+	return err
+}
+
+const insertPipelineReference = `-- name: InsertPipelineReference :exec
+INSERT INTO pipeline_references (source_pipeline_id, ingest_processor_id, referenced_pipeline_name)
+VALUES (?, ?, ?)
+`
+
+type InsertPipelineReferenceParams struct {
+	SourcePipelineID       int64
+	IngestProcessorID      int64
+	ReferencedPipelineName string
+}
+
+func (q *Queries) InsertPipelineReference(ctx context.Context, arg InsertPipelineReferenceParams) error {
+	_, err := q.db.ExecContext(ctx, insertPipelineReference, arg.SourcePipelineID, arg.IngestProcessorID, arg.ReferencedPipelineName)
+	return err
+}
+
 const insertPolicyTemplate = `-- name: InsertPolicyTemplate :one
 INSERT INTO policy_templates (integration_id, name, title, description, type,
                               deployment_modes_default_enabled,
@@ -556,8 +669,9 @@ func (q *Queries) InsertPolicyTemplateDataStream(ctx context.Context, arg Insert
 }
 
 const insertPolicyTemplateIcon = `-- name: InsertPolicyTemplateIcon :one
-INSERT INTO policy_template_icons (policy_template_id, src, title, size, type, dark_mode)
-VALUES (?, ?, ?, ?, ?, ?) RETURNING id
+INSERT INTO policy_template_icons (policy_template_id, src, title, size, type, dark_mode, icon_width,
+                                   icon_height, icon_byte_size)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id
 `
 
 type InsertPolicyTemplateIconParams struct {
@@ -567,6 +681,9 @@ type InsertPolicyTemplateIconParams struct {
 	Size             sql.NullString
 	Type             sql.NullString
 	DarkMode         sql.NullBool
+	IconWidth        sql.NullInt64
+	IconHeight       sql.NullInt64
+	IconByteSize     sql.NullInt64
 }
 
 func (q *Queries) InsertPolicyTemplateIcon(ctx context.Context, arg InsertPolicyTemplateIconParams) (int64, error) {
@@ -577,6 +694,9 @@ func (q *Queries) InsertPolicyTemplateIcon(ctx context.Context, arg InsertPolicy
 		arg.Size,
 		arg.Type,
 		arg.DarkMode,
+		arg.IconWidth,
+		arg.IconHeight,
+		arg.IconByteSize,
 	)
 	var id int64
 	err := row.Scan(&id)
@@ -677,6 +797,22 @@ func (q *Queries) InsertPolicyTemplateVar(ctx context.Context, arg InsertPolicyT
 	return err
 }
 
+const insertProcessorFieldWrite = `-- name: InsertProcessorFieldWrite :exec
+INSERT INTO processor_field_writes (ingest_processor_id, operation, field_name)
+VALUES (?, ?, ?)
+`
+
+type InsertProcessorFieldWriteParams struct {
+	IngestProcessorID int64
+	Operation         string
+	FieldName         string
+}
+
+func (q *Queries) InsertProcessorFieldWrite(ctx context.Context, arg InsertProcessorFieldWriteParams) error {
+	_, err := q.db.ExecContext(ctx, insertProcessorFieldWrite, arg.IngestProcessorID, arg.Operation, arg.FieldName)
+	return err
+}
+
 const insertRelease = `-- name: InsertRelease :one
 INSERT INTO releases (changelog_id, version, file_path, line_number, col)
 VALUES (?, ?, ?, ?, ?) RETURNING id
@@ -721,6 +857,22 @@ func (q *Queries) InsertSampleEvent(ctx context.Context, arg InsertSampleEventPa
 	return id, err
 }
 
+const insertSampleEventField = `-- name: InsertSampleEventField :exec
+INSERT INTO sample_event_fields (sample_event_id, data_stream_id, field_name)
+VALUES (?, ?, ?)
+`
+
+type InsertSampleEventFieldParams struct {
+	SampleEventID int64
+	DataStreamID  int64
+	FieldName     string
+}
+
+func (q *Queries) InsertSampleEventField(ctx context.Context, arg InsertSampleEventFieldParams) error {
+	_, err := q.db.ExecContext(ctx, insertSampleEventField, arg.SampleEventID, arg.DataStreamID, arg.FieldName)
+	return err
+}
+
 const insertStream = `-- name: InsertStream :one
 INSERT INTO streams (data_stream_id, input, description, title, template_path,
                      enabled)
@@ -968,3 +1120,137 @@ func (q *Queries) InsertVarOption(ctx context.Context, arg InsertVarOptionParams
 	err := row.Scan(&id)
 	return id, err
 }
+
+const listDuplicateDataStreamFields = `-- name: ListDuplicateDataStreamFields :many
+SELECT fields.name, COUNT(DISTINCT data_stream_fields.fields_file_name) AS file_count
+FROM data_stream_fields
+         JOIN fields ON fields.id = data_stream_fields.field_id
+WHERE data_stream_fields.data_stream_id = ?
+GROUP BY fields.name
+HAVING file_count > 1
+ORDER BY fields.name
+`
+
+type ListDuplicateDataStreamFieldsRow struct {
+	Name      string
+	FileCount int64
+}
+
+func (q *Queries) ListDuplicateDataStreamFields(ctx context.Context, dataStreamID int64) ([]ListDuplicateDataStreamFieldsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDuplicateDataStreamFields, dataStreamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDuplicateDataStreamFieldsRow
+	for rows.Next() {
+		var i ListDuplicateDataStreamFieldsRow
+		if err := rows.Scan(&i.Name, &i.FileCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const populateEcsVersionRefs = `-- name: PopulateEcsVersionRefs :exec
+INSERT INTO ecs_version_refs (integration_id, ecs_reference)
+SELECT DISTINCT integration_id, dependencies_ecs_reference
+FROM build_manifests
+WHERE dependencies_ecs_reference IS NOT NULL
+  AND dependencies_ecs_reference != ''
+`
+
+func (q *Queries) PopulateEcsVersionRefs(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, populateEcsVersionRefs)
+	return err
+}
+
+const populateFieldsFTS = `-- name: PopulateFieldsFTS :exec
+INSERT INTO fields_fts (rowid, name, description)
+SELECT id, name, description
+FROM fields
+`
+
+func (q *Queries) PopulateFieldsFTS(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, populateFieldsFTS)
+	return err
+}
+
+const populateIntegrationReadmeFTS = `-- name: PopulateIntegrationReadmeFTS :exec
+INSERT INTO integration_readme_fts (rowid, content)
+SELECT id, content
+FROM integration_readme
+`
+
+func (q *Queries) PopulateIntegrationReadmeFTS(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, populateIntegrationReadmeFTS)
+	return err
+}
+
+const populateIntegrationStats = `-- name: PopulateIntegrationStats :exec
+INSERT INTO integration_stats (integration_id, total_data_streams, total_fields, total_pipelines,
+                               total_processors, total_transforms, total_changelog_entries)
+SELECT integrations.id,
+       (SELECT COUNT(*)
+        FROM data_streams
+        WHERE data_streams.integration_id = integrations.id),
+       (SELECT COUNT(DISTINCT data_stream_fields.data_stream_id || ':' || fields.name)
+        FROM data_stream_fields
+                 JOIN fields ON fields.id = data_stream_fields.field_id
+                 JOIN data_streams ON data_streams.id = data_stream_fields.data_stream_id
+        WHERE data_streams.integration_id = integrations.id),
+       (SELECT COUNT(*)
+        FROM ingest_pipelines
+                 JOIN data_streams ON data_streams.id = ingest_pipelines.data_stream_id
+        WHERE data_streams.integration_id = integrations.id),
+       (SELECT COUNT(*)
+        FROM ingest_processors
+                 JOIN ingest_pipelines ON ingest_pipelines.id = ingest_processors.ingest_pipeline_id
+                 JOIN data_streams ON data_streams.id = ingest_pipelines.data_stream_id
+        WHERE data_streams.integration_id = integrations.id),
+       (SELECT COUNT(*)
+        FROM transforms
+        WHERE transforms.integration_id = integrations.id),
+       (SELECT COUNT(*)
+        FROM changes
+                 JOIN releases ON releases.id = changes.release_id
+                 JOIN changelogs ON changelogs.id = releases.changelog_id
+        WHERE changelogs.integration_id = integrations.id)
+FROM integrations
+`
+
+func (q *Queries) PopulateIntegrationStats(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, populateIntegrationStats)
+	return err
+}
+
+const populateIntegrationsFTS = `-- name: PopulateIntegrationsFTS :exec
+INSERT INTO integrations_fts (rowid, name, title, description)
+SELECT id, name, title, description
+FROM integrations
+`
+
+func (q *Queries) PopulateIntegrationsFTS(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, populateIntegrationsFTS)
+	return err
+}
+
+const updateIngestPipelineProcessorCount = `-- name: UpdateIngestPipelineProcessorCount :exec
+UPDATE ingest_pipelines
+SET processor_count = (SELECT COUNT(*)
+                       FROM ingest_processors
+                       WHERE ingest_processors.ingest_pipeline_id = ingest_pipelines.id)
+WHERE id = ?
+`
+
+func (q *Queries) UpdateIngestPipelineProcessorCount(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, updateIngestPipelineProcessorCount, id)
+	return err
+}
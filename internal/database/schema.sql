@@ -24,6 +24,42 @@ CREATE TABLE IF NOT EXISTS integrations (
     file_path TEXT NOT NULL -- path to the integration directory
 );
 
+CREATE INDEX IF NOT EXISTS idx_integrations_name ON integrations(name);
+
+-- README documentation (docs/README.md) of integration packages. Related to integrations via foreign key.
+CREATE TABLE IF NOT EXISTS integration_readme (
+    id INTEGER PRIMARY KEY AUTOINCREMENT, -- unique identifier
+    integration_id INTEGER NOT NULL, -- foreign key to integrations table
+    content TEXT NOT NULL, -- markdown content of the README (truncated to 500 KB)
+    truncated BOOLEAN NOT NULL, -- whether the content was truncated
+    file_path TEXT NOT NULL, -- path to the README file
+    FOREIGN KEY (integration_id) REFERENCES integrations(id)
+);
+
+-- Summary statistics of each integration package, computed after all packages are written. Related to integrations via foreign key.
+CREATE TABLE IF NOT EXISTS integration_stats (
+    integration_id INTEGER PRIMARY KEY, -- foreign key to integrations table
+    total_data_streams INTEGER NOT NULL, -- number of data streams
+    total_fields INTEGER NOT NULL, -- number of distinct field names summed over the data streams
+    total_pipelines INTEGER NOT NULL, -- number of ingest pipelines
+    total_processors INTEGER NOT NULL, -- number of ingest processors, including on_failure handlers
+    total_transforms INTEGER NOT NULL, -- number of transforms
+    total_changelog_entries INTEGER NOT NULL, -- number of changes in the changelog
+    FOREIGN KEY (integration_id) REFERENCES integrations(id)
+);
+
+-- Full-text search index over integration README content. The rowid is the id of the row in the integration_readme table.
+CREATE VIRTUAL TABLE IF NOT EXISTS integration_readme_fts USING fts5(
+    content -- markdown content of the README
+);
+
+-- Full-text search index over integration names, titles, and descriptions. The rowid is the id of the row in the integrations table.
+CREATE VIRTUAL TABLE IF NOT EXISTS integrations_fts USING fts5(
+    name, -- name of the package
+    title, -- title of the package
+    description -- description of the package
+);
+
 -- Policy templates offered by integration packages. Related to integrations via foreign key.
 CREATE TABLE IF NOT EXISTS policy_templates (
     id INTEGER PRIMARY KEY AUTOINCREMENT, -- unique identifier
@@ -71,6 +107,8 @@ CREATE TABLE IF NOT EXISTS data_streams (
     FOREIGN KEY (integration_id) REFERENCES integrations(id)
 );
 
+CREATE INDEX IF NOT EXISTS idx_data_streams_name ON data_streams(name);
+
 -- Individual streams within data stream manifests. Related to data_streams via foreign key.
 CREATE TABLE IF NOT EXISTS streams (
     id INTEGER PRIMARY KEY AUTOINCREMENT, -- unique identifier
@@ -172,6 +210,14 @@ CREATE TABLE IF NOT EXISTS fields (
     col INTEGER NOT NULL -- character position in the file
 );
 
+CREATE INDEX IF NOT EXISTS idx_fields_name ON fields(name);
+
+-- Full-text search index over field names and descriptions. The rowid is the id of the row in the fields table.
+CREATE VIRTUAL TABLE IF NOT EXISTS fields_fts USING fts5(
+    name, -- name of the field
+    description -- description of the field
+);
+
 -- Elasticsearch transform configurations within integration packages.
 CREATE TABLE IF NOT EXISTS transforms (
     id INTEGER PRIMARY KEY AUTOINCREMENT, -- unique identifier
@@ -188,6 +234,7 @@ CREATE TABLE IF NOT EXISTS transforms (
     transform_pivot_aggs TEXT, -- alternative name for aggregations (JSON)
     transform_latest_sort TEXT, -- sort field for determining the latest documents
     transform_latest_unique_key TEXT, -- unique key fields (JSON array)
+    transform_type TEXT GENERATED ALWAYS AS (CASE WHEN transform_pivot_group_by IS NOT NULL THEN 'pivot' WHEN transform_latest_sort IS NOT NULL THEN 'latest' ELSE 'unknown' END) STORED, -- transform type derived from the pivot and latest settings (pivot, latest, or unknown)
     transform_description TEXT, -- description of the transform
     transform_frequency TEXT, -- frequency of the transform execution
     transform_settings_dates_as_epoch_millis BOOLEAN, -- whether dates should be stored as epoch milliseconds
@@ -255,6 +302,9 @@ CREATE TABLE IF NOT EXISTS integration_icons (
     size TEXT, -- size specification
     type TEXT, -- MIME type of the icon
     dark_mode BOOLEAN, -- whether the icon is for dark mode
+    icon_width INTEGER, -- width in pixels computed from the file (NULL for unsupported formats such as SVG)
+    icon_height INTEGER, -- height in pixels computed from the file (NULL for unsupported formats such as SVG)
+    icon_byte_size INTEGER, -- size in bytes (NULL for unsupported formats such as SVG)
     FOREIGN KEY (integration_id) REFERENCES integrations(id)
 );
 
@@ -281,6 +331,9 @@ CREATE TABLE IF NOT EXISTS policy_template_icons (
     size TEXT, -- size specification
     type TEXT, -- MIME type of the icon
     dark_mode BOOLEAN, -- whether the icon is for dark mode
+    icon_width INTEGER, -- width in pixels computed from the file (NULL for unsupported formats such as SVG)
+    icon_height INTEGER, -- height in pixels computed from the file (NULL for unsupported formats such as SVG)
+    icon_byte_size INTEGER, -- size in bytes (NULL for unsupported formats such as SVG)
     FOREIGN KEY (policy_template_id) REFERENCES policy_templates(id)
 );
 
@@ -353,6 +406,24 @@ CREATE TABLE IF NOT EXISTS build_manifests (
     FOREIGN KEY (integration_id) REFERENCES integrations(id)
 );
 
+-- Packages that integrations depend on. The package manifest has no field listing other packages, so these are
+-- derived from the manifest conditions (e.g. conditions.kibana.version). Related to integrations via foreign key.
+CREATE TABLE IF NOT EXISTS package_dependencies (
+    integration_id INTEGER NOT NULL, -- foreign key to integrations table
+    dependency_name TEXT NOT NULL, -- name of the required package (e.g. kibana)
+    dependency_version_range TEXT NOT NULL, -- semantic version constraint (e.g. ^8.14.0 || ^9.0.0)
+    PRIMARY KEY (integration_id, dependency_name),
+    FOREIGN KEY (integration_id) REFERENCES integrations(id)
+);
+
+-- ECS versions referenced by integrations, derived from build_manifests after all packages are written. Related to integrations via foreign key.
+CREATE TABLE IF NOT EXISTS ecs_version_refs (
+    integration_id INTEGER NOT NULL, -- foreign key to integrations table
+    ecs_reference TEXT NOT NULL, -- ECS source reference (e.g. git@v8.11.0)
+    PRIMARY KEY (integration_id, ecs_reference),
+    FOREIGN KEY (integration_id) REFERENCES integrations(id)
+);
+
 -- Version history for integration packages. Related to integrations via foreign key.
 CREATE TABLE IF NOT EXISTS changelogs (
     id INTEGER PRIMARY KEY AUTOINCREMENT, -- unique identifier
@@ -394,6 +465,7 @@ CREATE TABLE IF NOT EXISTS ingest_pipelines (
     version INTEGER, -- version number used by external systems to track ingest pipelines
     meta TEXT, -- optional metadata about the ingest pipeline (JSON)
     file_path TEXT NOT NULL, -- path to the ingest node pipeline file
+    processor_count INTEGER, -- number of processors in the pipeline, including on_failure handlers
     FOREIGN KEY (data_stream_id) REFERENCES data_streams(id)
 );
 
@@ -404,12 +476,57 @@ CREATE TABLE IF NOT EXISTS ingest_processors (
     type TEXT NOT NULL, -- ingest processor type
     attributes JSON, -- processor configuration (JSON)
     json_pointer TEXT NOT NULL, -- JSON Pointer (RFC 6901) location within the pipeline (e.g. '/processors/12/append' or '/on_failure/1/append').
+    depth INTEGER NOT NULL DEFAULT 0, -- number of on_failure handlers enclosing the processor (0 for a top-level processor)
     file_path TEXT NOT NULL, -- file path where the processor is defined
     line_number INTEGER NOT NULL, -- line number in the file
     col INTEGER NOT NULL, -- character position in the file
     FOREIGN KEY (ingest_pipeline_id) REFERENCES ingest_pipelines(id)
 );
 
+-- Grok expressions and custom pattern definitions used by grok processors in ingest pipelines. Related to integrations and ingest_processors via foreign key.
+CREATE TABLE IF NOT EXISTS grok_patterns (
+    id INTEGER PRIMARY KEY AUTOINCREMENT, -- unique identifier
+    integration_id INTEGER NOT NULL, -- foreign key to integrations table
+    ingest_processor_id INTEGER NOT NULL, -- foreign key to ingest_processors table
+    pipeline_name TEXT, -- name of the ingest pipeline containing the grok processor
+    field TEXT, -- field parsed by the grok processor
+    pattern TEXT NOT NULL, -- grok expression from 'patterns', or the expression of a custom pattern from 'pattern_definitions'
+    definition_name TEXT, -- name of the custom pattern for entries from 'pattern_definitions' (NULL for entries from 'patterns')
+    FOREIGN KEY (integration_id) REFERENCES integrations(id),
+    FOREIGN KEY (ingest_processor_id) REFERENCES ingest_processors(id)
+);
+
+-- Patterns used by dissect processors in ingest pipelines. Related to integrations and ingest_processors via foreign key.
+CREATE TABLE IF NOT EXISTS dissect_patterns (
+    id INTEGER PRIMARY KEY AUTOINCREMENT, -- unique identifier
+    integration_id INTEGER NOT NULL, -- foreign key to integrations table
+    ingest_processor_id INTEGER NOT NULL, -- foreign key to ingest_processors table
+    pipeline_name TEXT, -- name of the ingest pipeline containing the dissect processor
+    field TEXT, -- field parsed by the dissect processor
+    tokenizer TEXT NOT NULL, -- dissect pattern from the processor's 'pattern' attribute (e.g. '%{source.ip} - %{user.name}')
+    FOREIGN KEY (integration_id) REFERENCES integrations(id),
+    FOREIGN KEY (ingest_processor_id) REFERENCES ingest_processors(id)
+);
+
+-- Fields written by set, append, and rename ingest processors. Related to ingest_processors via foreign key.
+CREATE TABLE IF NOT EXISTS processor_field_writes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT, -- unique identifier
+    ingest_processor_id INTEGER NOT NULL, -- foreign key to ingest_processors table
+    operation TEXT NOT NULL, -- how the field is written (set, append, rename_from, or rename_to)
+    field_name TEXT NOT NULL, -- name of the field
+    FOREIGN KEY (ingest_processor_id) REFERENCES ingest_processors(id)
+);
+
+-- Pipelines called by pipeline processors. Related to ingest_pipelines and ingest_processors via foreign key.
+CREATE TABLE IF NOT EXISTS pipeline_references (
+    id INTEGER PRIMARY KEY AUTOINCREMENT, -- unique identifier
+    source_pipeline_id INTEGER NOT NULL, -- foreign key to the ingest_pipelines table for the calling pipeline
+    ingest_processor_id INTEGER NOT NULL, -- foreign key to ingest_processors table for the pipeline processor
+    referenced_pipeline_name TEXT NOT NULL, -- name attribute of the pipeline processor, with '{{ IngestPipeline "x" }}' templates resolved to 'x'
+    FOREIGN KEY (source_pipeline_id) REFERENCES ingest_pipelines(id),
+    FOREIGN KEY (ingest_processor_id) REFERENCES ingest_processors(id)
+);
+
 -- Sample event data for data streams. Related to data_streams via foreign key.
 CREATE TABLE IF NOT EXISTS sample_events (
     id INTEGER PRIMARY KEY AUTOINCREMENT, -- unique identifier
@@ -418,3 +535,13 @@ CREATE TABLE IF NOT EXISTS sample_events (
     file_path TEXT NOT NULL, -- path to the sample event file
     FOREIGN KEY (data_stream_id) REFERENCES data_streams(id)
 );
+
+-- Field paths present in sample events. Related to sample_events and data_streams via foreign key.
+CREATE TABLE IF NOT EXISTS sample_event_fields (
+    id INTEGER PRIMARY KEY AUTOINCREMENT, -- unique identifier
+    sample_event_id INTEGER NOT NULL, -- foreign key to sample_events table
+    data_stream_id INTEGER NOT NULL, -- foreign key to data_streams table
+    field_name TEXT NOT NULL, -- dot-notation path of a leaf value in the sample event (e.g. 'event.dataset')
+    FOREIGN KEY (sample_event_id) REFERENCES sample_events(id),
+    FOREIGN KEY (data_stream_id) REFERENCES data_streams(id)
+);
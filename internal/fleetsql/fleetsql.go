@@ -10,9 +10,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/andrewkroh/go-ecs"
 	"github.com/andrewkroh/go-fleetpkg"
@@ -20,29 +23,117 @@ import (
 	"github.com/andrewkroh/fleetpkg-mcp/internal/database"
 )
 
-// TableSchemas returns a slice of SQL table creation statements.
-// The statements include comments explaining the table's purpose and
-// details about each column.
+var inMemoryDBCount atomic.Int64
+
+// InMemoryDSN returns a SQLite data source name for a new, ephemeral
+// in-memory database. The shared cache allows all connections from a *sql.DB
+// to see the same data, and each call returns a distinct name so that
+// databases built concurrently or on reload do not collide. The database is
+// discarded when the last connection is closed.
+func InMemoryDSN() string {
+	return fmt.Sprintf("file:fleetpkg-%d?mode=memory&cache=shared", inMemoryDBCount.Add(1))
+}
+
+// TableSchemas returns a slice of SQL table creation statements followed by
+// the index creation statements. The table statements include comments
+// explaining the table's purpose and details about each column.
 func TableSchemas() []string {
-	return database.Creates[:]
+	return append(database.Creates[:], database.Indexes[:]...)
+}
+
+// WriteOptions controls how WritePackages writes packages.
+type WriteOptions struct {
+	// Workers is the number of packages inserted concurrently. A value less
+	// than 1 inserts them serially.
+	Workers int
+
+	// Strict makes a field that is defined in more than one fields file of
+	// a data stream an error. Otherwise a warning is logged.
+	Strict bool
+
+	// Log receives warnings about the packages. It defaults to slog.Default().
+	Log *slog.Logger
 }
 
 // WritePackages writes integration packages into the database.
-// It creates the necessary tables and inserts each package in a transaction.
-// Returns an error if table creation or package insertion fails.
-func WritePackages(ctx context.Context, db *sql.DB, pkgs []fleetpkg.Integration) error {
-	// Create tables (assumes they do not exist).
+// It creates the necessary tables and then inserts each package in its own
+// transaction. Up to opts.Workers packages are inserted concurrently. Returns
+// an error if table creation or package insertion fails.
+func WritePackages(ctx context.Context, db *sql.DB, pkgs []fleetpkg.Integration, opts WriteOptions) error {
+	// Create tables (assumes they do not exist). This must complete before
+	// any package is inserted.
 	if err := createTables(ctx, db); err != nil {
 		return fmt.Errorf("failed creating tables: %w", err)
 	}
 
-	// Write each package to DB in a TX.
-	for _, in := range pkgs {
-		if err := insertPackage(ctx, db, &in); err != nil {
-			return fmt.Errorf("failed inserting %q: %w", filepath.Base(in.Path()), err)
+	workers := max(opts.Workers, 1)
+	if opts.Log == nil {
+		opts.Log = slog.Default()
+	}
+
+	// Stop starting new inserts after the first failure.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, workers)
+	errs := make(chan error, len(pkgs))
+	var wg sync.WaitGroup
+	for i := range pkgs {
+		in := &pkgs[i]
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
 		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Go(func() {
+			defer func() { <-sem }()
+
+			if err := insertPackage(ctx, db, in, opts); err != nil {
+				errs <- fmt.Errorf("failed inserting %q: %w", filepath.Base(in.Path()), err)
+				cancel()
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	// The first error is the root cause. Any that follow are usually the
+	// result of the cancellation.
+	if err, ok := <-errs; ok {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
+	// Indexes are built once all packages have been written because that is
+	// faster than maintaining them during the inserts.
+	if err := createIndexes(ctx, db); err != nil {
+		return fmt.Errorf("failed creating indexes: %w", err)
+	}
+
+	// Likewise the full-text indexes and the summary tables are populated from
+	// the written rows.
+	q := database.New(db)
+	if err := q.PopulateFieldsFTS(ctx); err != nil {
+		return fmt.Errorf("failed populating fields_fts: %w", err)
+	}
+	if err := q.PopulateIntegrationsFTS(ctx); err != nil {
+		return fmt.Errorf("failed populating integrations_fts: %w", err)
+	}
+	if err := q.PopulateIntegrationReadmeFTS(ctx); err != nil {
+		return fmt.Errorf("failed populating integration_readme_fts: %w", err)
+	}
+	if err := q.PopulateIntegrationStats(ctx); err != nil {
+		return fmt.Errorf("failed populating integration_stats: %w", err)
+	}
+	if err := q.PopulateEcsVersionRefs(ctx); err != nil {
+		return fmt.Errorf("failed populating ecs_version_refs: %w", err)
+	}
 	return nil
 }
 
@@ -62,7 +153,79 @@ func createTables(ctx context.Context, db *sql.DB) (err error) {
 	return nil
 }
 
-func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration) (err error) {
+// insertProcessorMetadata records the patterns of grok and dissect
+// processors, the fields written by set, append, and rename processors, and
+// the pipelines called by pipeline processors. Other processor types are
+// ignored.
+func insertProcessorMetadata(ctx context.Context, q *database.Queries, integID, pipelineID, procID int64, pipelineName string, proc FlatProcessor) error {
+	for _, gp := range proc.GrokPatterns() {
+		err := q.InsertGrokPattern(ctx, database.InsertGrokPatternParams{
+			IntegrationID:     integID,
+			IngestProcessorID: procID,
+			PipelineName:      sqlStringEmtpyIsNull(pipelineName),
+			Field:             sqlStringEmtpyIsNull(gp.Field),
+			Pattern:           gp.Pattern,
+			DefinitionName:    sqlStringEmtpyIsNull(gp.DefinitionName),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert grok pattern at %s: %w", proc.JSONPointer, err)
+		}
+	}
+
+	if dp, ok := proc.DissectPattern(); ok {
+		err := q.InsertDissectPattern(ctx, database.InsertDissectPatternParams{
+			IntegrationID:     integID,
+			IngestProcessorID: procID,
+			PipelineName:      sqlStringEmtpyIsNull(pipelineName),
+			Field:             sqlStringEmtpyIsNull(dp.Field),
+			Tokenizer:         dp.Tokenizer,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert dissect pattern at %s: %w", proc.JSONPointer, err)
+		}
+	}
+
+	for _, fw := range proc.FieldWrites() {
+		err := q.InsertProcessorFieldWrite(ctx, database.InsertProcessorFieldWriteParams{
+			IngestProcessorID: procID,
+			Operation:         fw.Operation,
+			FieldName:         fw.FieldName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert field write at %s: %w", proc.JSONPointer, err)
+		}
+	}
+
+	if ref, ok := proc.PipelineReference(); ok {
+		err := q.InsertPipelineReference(ctx, database.InsertPipelineReferenceParams{
+			SourcePipelineID:       pipelineID,
+			IngestProcessorID:      procID,
+			ReferencedPipelineName: ref,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert pipeline reference at %s: %w", proc.JSONPointer, err)
+		}
+	}
+	return nil
+}
+
+// createIndexes creates the database indexes if they do not exist.
+func createIndexes(ctx context.Context, db *sql.DB) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer txDone(tx, &err)
+
+	for _, idx := range database.Indexes {
+		if _, err := tx.ExecContext(ctx, idx); err != nil {
+			return fmt.Errorf("failed creating index: %q: %w", idx, err)
+		}
+	}
+	return nil
+}
+
+func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration, opts WriteOptions) (err error) {
 	tx, err := db.Begin()
 	if err != nil {
 		return err
@@ -86,8 +249,41 @@ func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration) (e
 		}
 	}
 
+	// Package dependencies. The manifest does not list other packages so
+	// the Kibana version condition is the only dependency recorded.
+	if v := in.Manifest.Conditions.Kibana.Version; v != "" {
+		err = q.InsertPackageDependency(ctx, database.InsertPackageDependencyParams{
+			IntegrationID:          integID,
+			DependencyName:         "kibana",
+			DependencyVersionRange: v,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Integration README.
+	readme, ok, err := ReadReadme(in.Path())
+	if err != nil {
+		return fmt.Errorf("failed to read README: %w", err)
+	}
+	if ok {
+		err = q.InsertIntegrationReadme(ctx, database.InsertIntegrationReadmeParams{
+			IntegrationID: integID,
+			Content:       readme.Content,
+			Truncated:     readme.Truncated,
+			FilePath:      readme.Path,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// Integration icons.
 	for _, icon := range in.Manifest.Icons {
+		// Read image metadata from file
+		imgMeta := ReadImageMetadata(in.Path(), icon.Src)
+
 		_, err = q.InsertIntegrationIcon(ctx, database.InsertIntegrationIconParams{
 			IntegrationID: integID,
 			Src:           sqlStringEmtpyIsNull(icon.Src),
@@ -95,6 +291,9 @@ func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration) (e
 			Size:          sqlStringEmtpyIsNull(icon.Size),
 			Type:          sqlStringEmtpyIsNull(icon.Type),
 			DarkMode:      sqlNullBool(icon.DarkMode),
+			IconWidth:     sqlNullInt64FromInt(imgMeta.Width),
+			IconHeight:    sqlNullInt64FromInt(imgMeta.Height),
+			IconByteSize:  sqlNullInt64FromInt64(imgMeta.ByteSize),
 		})
 		if err != nil {
 			return err
@@ -194,6 +393,9 @@ func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration) (e
 
 		// Policy template icons.
 		for _, icon := range pt.Icons {
+			// Read image metadata from file
+			imgMeta := ReadImageMetadata(in.Path(), icon.Src)
+
 			_, err = q.InsertPolicyTemplateIcon(ctx, database.InsertPolicyTemplateIconParams{
 				PolicyTemplateID: ptID,
 				Src:              sqlStringEmtpyIsNull(icon.Src),
@@ -201,6 +403,9 @@ func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration) (e
 				Size:             sqlStringEmtpyIsNull(icon.Size),
 				Type:             sqlStringEmtpyIsNull(icon.Type),
 				DarkMode:         sqlNullBool(icon.DarkMode),
+				IconWidth:        sqlNullInt64FromInt(imgMeta.Width),
+				IconHeight:       sqlNullInt64FromInt(imgMeta.Height),
+				IconByteSize:     sqlNullInt64FromInt64(imgMeta.ByteSize),
 			})
 			if err != nil {
 				return err
@@ -333,6 +538,10 @@ func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration) (e
 			}
 		}
 
+		if err = checkDuplicateFields(ctx, q, dsID, in, ds, opts); err != nil {
+			return err
+		}
+
 		// Data stream ingest pipelines.
 		for name, pipeline := range ds.Pipelines {
 			pipelineID, err := q.InsertIngestPipeline(ctx, database.InsertIngestPipelineParams{
@@ -358,11 +567,12 @@ func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration) (e
 					return fmt.Errorf("failed to marshal processor attributes: %w", err)
 				}
 
-				_, err = q.InsertIngestProcessor(ctx, database.InsertIngestProcessorParams{
+				procID, err := q.InsertIngestProcessor(ctx, database.InsertIngestProcessorParams{
 					IngestPipelineID: pipelineID,
 					Type:             proc.Type,
 					Attributes:       sqlStringEmtpyIsNull(attrs),
 					JsonPointer:      proc.JSONPointer,
+					Depth:            int64(proc.Depth()),
 					FilePath:         proc.FilePath,
 					LineNumber:       int64(proc.Line),
 					Col:              int64(proc.Column),
@@ -370,6 +580,9 @@ func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration) (e
 				if err != nil {
 					return fmt.Errorf("failed to insert processor %s at %s: %w", proc.Type, proc.JSONPointer, err)
 				}
+				if err = insertProcessorMetadata(ctx, q, integID, pipelineID, procID, name, proc); err != nil {
+					return err
+				}
 			}
 
 			// Flatten and insert global on_failure processors.
@@ -384,11 +597,12 @@ func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration) (e
 						return fmt.Errorf("failed to marshal on_failure processor attributes: %w", err)
 					}
 
-					_, err = q.InsertIngestProcessor(ctx, database.InsertIngestProcessorParams{
+					procID, err := q.InsertIngestProcessor(ctx, database.InsertIngestProcessorParams{
 						IngestPipelineID: pipelineID,
 						Type:             proc.Type,
 						Attributes:       sqlStringEmtpyIsNull(attrs),
 						JsonPointer:      proc.JSONPointer,
+						Depth:            int64(proc.Depth()),
 						FilePath:         proc.FilePath,
 						LineNumber:       int64(proc.Line),
 						Col:              int64(proc.Column),
@@ -396,8 +610,16 @@ func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration) (e
 					if err != nil {
 						return fmt.Errorf("failed to insert on_failure processor %s at %s: %w", proc.Type, proc.JSONPointer, err)
 					}
+					if err = insertProcessorMetadata(ctx, q, integID, pipelineID, procID, name, proc); err != nil {
+						return err
+					}
 				}
 			}
+
+			// Store the count so that queries need not aggregate processors.
+			if err = q.UpdateIngestPipelineProcessorCount(ctx, pipelineID); err != nil {
+				return err
+			}
 		}
 
 		// Data stream sample event.
@@ -406,7 +628,7 @@ func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration) (e
 			if err != nil {
 				return fmt.Errorf("failed to marshal sample event: %w", err)
 			}
-			_, err = q.InsertSampleEvent(ctx, database.InsertSampleEventParams{
+			sampleEventID, err := q.InsertSampleEvent(ctx, database.InsertSampleEventParams{
 				DataStreamID: dsID,
 				Event:        eventJSON,
 				FilePath:     ds.SampleEvent.Path(),
@@ -414,6 +636,22 @@ func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration) (e
 			if err != nil {
 				return err
 			}
+
+			// Sample event field paths.
+			paths, err := SampleEventFieldPaths(eventJSON)
+			if err != nil {
+				return fmt.Errorf("failed to read sample event fields: %w", err)
+			}
+			for _, p := range paths {
+				err = q.InsertSampleEventField(ctx, database.InsertSampleEventFieldParams{
+					SampleEventID: sampleEventID,
+					DataStreamID:  dsID,
+					FieldName:     p,
+				})
+				if err != nil {
+					return err
+				}
+			}
 		}
 	}
 
@@ -494,6 +732,28 @@ func insertPackage(ctx context.Context, db *sql.DB, in *fleetpkg.Integration) (e
 	return nil
 }
 
+// checkDuplicateFields reports fields that are defined in more than one fields
+// file of the data stream. They are logged as warnings unless opts.Strict is
+// set, in which case the first one is returned as an error.
+func checkDuplicateFields(ctx context.Context, q *database.Queries, dsID int64, in *fleetpkg.Integration, ds *fleetpkg.DataStream, opts WriteOptions) error {
+	dupes, err := q.ListDuplicateDataStreamFields(ctx, dsID)
+	if err != nil {
+		return err
+	}
+	dsName := filepath.Base(ds.Path())
+	for _, d := range dupes {
+		if opts.Strict {
+			return fmt.Errorf("field %q is defined in %d fields files of data stream %q", d.Name, d.FileCount, dsName)
+		}
+		opts.Log.Warn("Field is defined in more than one fields file",
+			slog.String("package", in.Manifest.Name),
+			slog.String("data_stream", dsName),
+			slog.String("field", d.Name),
+			slog.Int64("file_count", d.FileCount))
+	}
+	return nil
+}
+
 func insertManifest(ctx context.Context, q *database.Queries, in *fleetpkg.Integration) (int64, error) {
 	m := in.Manifest
 	p := database.InsertIntegrationParams{
@@ -5,16 +5,23 @@
 package fleetsql
 
 import (
+	"bytes"
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/andrewkroh/go-fleetpkg"
 
+	"github.com/andrewkroh/fleetpkg-mcp/internal/database"
+
 	// Register SQLite database driver.
 	_ "modernc.org/sqlite"
 )
@@ -48,7 +55,7 @@ func TestWritePackages(t *testing.T) {
 	}()
 
 	// Write packages.
-	if err = WritePackages(t.Context(), db, pkgs); err != nil {
+	if err = WritePackages(t.Context(), db, pkgs, WriteOptions{Workers: 1}); err != nil {
 		t.Fatal(err)
 	}
 
@@ -66,6 +73,510 @@ func TestWritePackages(t *testing.T) {
 	}
 }
 
+func TestWritePackagesInMemory(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	db, err := sql.Open("sqlite", InMemoryDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = WritePackages(t.Context(), db, nil, WriteOptions{Workers: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tables must be visible through the same *sql.DB.
+	var count int
+	if err = db.QueryRowContext(t.Context(), `SELECT count(*) FROM sqlite_master WHERE type = 'table'`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 {
+		t.Fatal("expected tables to exist in the in-memory database")
+	}
+
+	// Nothing may be written to disk.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		t.Errorf("unexpected file written to disk: %s", e.Name())
+	}
+}
+
+func TestWritePackagesIconMetadata(t *testing.T) {
+	pkgs, err := loadPackages(slog.New(slog.DiscardHandler), "testdata/integrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", InMemoryDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = WritePackages(t.Context(), db, pkgs, WriteOptions{Workers: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var width, height, byteSize int64
+	err = db.QueryRowContext(t.Context(),
+		`SELECT icon_width, icon_height, icon_byte_size FROM integration_icons WHERE src = '/img/icon.png'`,
+	).Scan(&width, &height, &byteSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 32 || height != 32 {
+		t.Errorf("expected 32x32 icon, got %dx%d", width, height)
+	}
+	if byteSize == 0 {
+		t.Error("expected a non-zero icon byte size")
+	}
+}
+
+func TestWritePackagesReadme(t *testing.T) {
+	pkgs, err := loadPackages(slog.New(slog.DiscardHandler), "testdata/integrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", InMemoryDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = WritePackages(t.Context(), db, pkgs, WriteOptions{Workers: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var content, filePath string
+	var truncated bool
+	err = db.QueryRowContext(t.Context(), `
+		SELECT r.content, r.truncated, r.file_path
+		FROM integration_readme r JOIN integrations i ON i.id = r.integration_id
+		WHERE i.name = 'icons'`,
+	).Scan(&content, &truncated, &filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(content, "# Icons") {
+		t.Errorf("unexpected README content %q", content)
+	}
+	if truncated {
+		t.Error("expected README to not be truncated")
+	}
+	if filepath.Base(filePath) != "README.md" {
+		t.Errorf("unexpected README path %q", filePath)
+	}
+
+	var name string
+	err = db.QueryRowContext(t.Context(), `
+		SELECT i.name
+		FROM integration_readme_fts
+		  JOIN integration_readme r ON r.id = integration_readme_fts.rowid
+		  JOIN integrations i ON i.id = r.integration_id
+		WHERE integration_readme_fts MATCH 'fixture'`,
+	).Scan(&name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "icons" {
+		t.Errorf("expected FTS match for icons, got %q", name)
+	}
+}
+
+func TestInsertTransformType(t *testing.T) {
+	db, err := sql.Open("sqlite", InMemoryDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, stmt := range database.Creates {
+		if _, err = db.ExecContext(t.Context(), stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sort := "@timestamp"
+	transforms := map[string]*fleetpkg.ElasticsearchTransform{
+		"pivot":   {Pivot: &fleetpkg.TransformPivot{GroupBy: map[string]any{"host.name": map[string]any{}}}},
+		"latest":  {Latest: &fleetpkg.TransformLatest{Sort: &sort, UniqueKey: []string{"host.id"}}},
+		"unknown": {},
+	}
+
+	q := database.New(db)
+	for want, tr := range transforms {
+		id, err := insertTransform(t.Context(), q, 1, &fleetpkg.Transform{Transform: tr})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got string
+		if err = db.QueryRowContext(t.Context(), `SELECT transform_type FROM transforms WHERE id = ?`, id).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("expected transform_type %q, got %q", want, got)
+		}
+	}
+}
+
+func TestWritePackagesDuplicateFields(t *testing.T) {
+	pkgs, err := loadPackages(slog.New(slog.DiscardHandler), "testdata/duplicate_fields")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("warn", func(t *testing.T) {
+		db, err := sql.Open("sqlite", InMemoryDSN())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		var buf bytes.Buffer
+		log := slog.New(slog.NewTextHandler(&buf, nil))
+		if err = WritePackages(t.Context(), db, pkgs, WriteOptions{Workers: 1, Log: log}); err != nil {
+			t.Fatal(err)
+		}
+
+		logs := buf.String()
+		if n := strings.Count(logs, "level=WARN"); n != 1 {
+			t.Fatalf("expected 1 warning, got %d:\n%s", n, logs)
+		}
+		for _, want := range []string{"package=duplicate_fields", "data_stream=log", "field=message", "file_count=2"} {
+			if !strings.Contains(logs, want) {
+				t.Errorf("expected warning to contain %q:\n%s", want, logs)
+			}
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		db, err := sql.Open("sqlite", InMemoryDSN())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		err = WritePackages(t.Context(), db, pkgs, WriteOptions{Workers: 1, Strict: true})
+		if err == nil || !strings.Contains(err.Error(), `field "message" is defined in 2 fields files`) {
+			t.Fatalf("expected duplicate field error, got %v", err)
+		}
+	})
+}
+
+func TestWritePackagesIntegrationStats(t *testing.T) {
+	pkgs, err := loadPackages(slog.New(slog.DiscardHandler), "testdata/duplicate_fields")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", InMemoryDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = WritePackages(t.Context(), db, pkgs, WriteOptions{Workers: 1, Log: slog.New(slog.DiscardHandler)}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got database.IntegrationStat
+	err = db.QueryRowContext(t.Context(), `
+SELECT total_data_streams, total_fields, total_pipelines, total_processors, total_transforms, total_changelog_entries
+FROM integration_stats`).Scan(&got.TotalDataStreams, &got.TotalFields, &got.TotalPipelines,
+		&got.TotalProcessors, &got.TotalTransforms, &got.TotalChangelogEntries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The message field is defined in two fields files but counted once.
+	want := database.IntegrationStat{TotalDataStreams: 1, TotalFields: 3, TotalChangelogEntries: 1}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestWritePackagesDependencies(t *testing.T) {
+	pkgs, err := loadPackages(slog.New(slog.DiscardHandler), "testdata/integrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", InMemoryDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = WritePackages(t.Context(), db, pkgs, WriteOptions{Workers: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var name, versionRange string
+	err = db.QueryRowContext(t.Context(), `
+		SELECT d.dependency_name, d.dependency_version_range
+		FROM package_dependencies d JOIN integrations i ON i.id = d.integration_id
+		WHERE i.name = 'icons'`,
+	).Scan(&name, &versionRange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "kibana" || versionRange != "^8.14.0" {
+		t.Errorf("expected kibana ^8.14.0 dependency, got %s %s", name, versionRange)
+	}
+}
+
+func TestWritePackagesProcessorCount(t *testing.T) {
+	pkgs, err := loadPackages(slog.New(slog.DiscardHandler), "testdata/ingest_pipelines")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", InMemoryDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = WritePackages(t.Context(), db, pkgs, WriteOptions{Workers: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var processorCount, rowCount int64
+	err = db.QueryRowContext(t.Context(), `
+		SELECT p.processor_count, (SELECT COUNT(*) FROM ingest_processors WHERE ingest_pipeline_id = p.id)
+		FROM ingest_pipelines p`,
+	).Scan(&processorCount, &rowCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Three processors, two nested on_failure handlers, and one global
+	// on_failure handler.
+	if processorCount != 6 || processorCount != rowCount {
+		t.Errorf("expected processor_count 6 matching %d processor rows, got %d", rowCount, processorCount)
+	}
+}
+
+func TestWritePackagesProcessorDepth(t *testing.T) {
+	pkgs, err := loadPackages(slog.New(slog.DiscardHandler), "testdata/ingest_pipelines")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", InMemoryDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = WritePackages(t.Context(), db, pkgs, WriteOptions{Workers: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.QueryContext(t.Context(), `SELECT json_pointer, depth FROM ingest_processors`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	got := map[string]int{}
+	for rows.Next() {
+		var jsonPointer string
+		var depth int
+		if err = rows.Scan(&jsonPointer, &depth); err != nil {
+			t.Fatal(err)
+		}
+		got[jsonPointer] = depth
+	}
+	if err = rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{
+		"/processors/0/set":                                         0,
+		"/processors/1/rename":                                      0,
+		"/processors/1/rename/on_failure/0/append":                  1,
+		"/processors/1/rename/on_failure/0/append/on_failure/0/set": 2,
+		"/processors/2/remove":                                      0,
+		"/on_failure/0/set":                                         1,
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("expected depths %v, got %v", want, got)
+	}
+}
+
+func TestReadReadmeTruncated(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// Place a 3-byte character across the truncation boundary.
+	data := append(bytes.Repeat([]byte("a"), maxReadmeBytes-1), "€ tail"...)
+	if err := os.WriteFile(filepath.Join(dir, "docs", "README.md"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, ok, err := ReadReadme(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("expected README to be found")
+	}
+	if !r.Truncated {
+		t.Error("expected README to be truncated")
+	}
+	if len(r.Content) != maxReadmeBytes-1 {
+		t.Errorf("expected %d bytes, got %d", maxReadmeBytes-1, len(r.Content))
+	}
+	if !utf8.ValidString(r.Content) {
+		t.Error("truncated README is not valid UTF-8")
+	}
+
+	if _, ok, err = ReadReadme(t.TempDir()); err != nil || ok {
+		t.Errorf("expected no README, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestWritePackagesConcurrent(t *testing.T) {
+	pkgs := copiedPackages(t, 16)
+
+	serial := writtenRowCounts(t, pkgs, 1)
+	concurrent := writtenRowCounts(t, pkgs, 4)
+
+	if serial["integrations"] != int64(len(pkgs)) {
+		t.Fatalf("expected %d integrations, got %d", len(pkgs), serial["integrations"])
+	}
+	for table, want := range serial {
+		if got := concurrent[table]; got != want {
+			t.Errorf("table %s: expected %d rows, got %d", table, want, got)
+		}
+	}
+}
+
+func BenchmarkWritePackages(b *testing.B) {
+	integrationsDir := os.Getenv("INTEGRATIONS_DIR")
+	if integrationsDir == "" {
+		b.Skip("INTEGRATIONS_DIR env var is not set.")
+	}
+
+	pkgs, err := loadPackages(slog.New(slog.DiscardHandler), integrationsDir)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, workers := range []int{1, runtime.NumCPU() / 2, runtime.NumCPU()} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for b.Loop() {
+				db, err := sql.Open("sqlite", InMemoryDSN())
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err = WritePackages(b.Context(), db, pkgs, WriteOptions{Workers: workers, Log: slog.New(slog.DiscardHandler)}); err != nil {
+					b.Fatal(err)
+				}
+				db.Close()
+			}
+		})
+	}
+}
+
+func BenchmarkQueryFieldByName(b *testing.B) {
+	for _, indexed := range []bool{false, true} {
+		b.Run(fmt.Sprintf("indexed=%v", indexed), func(b *testing.B) {
+			db, err := sql.Open("sqlite", InMemoryDSN())
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer db.Close()
+
+			if err = createTables(b.Context(), db); err != nil {
+				b.Fatal(err)
+			}
+			_, err = db.ExecContext(b.Context(), `
+WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 50000)
+INSERT INTO fields (name, file_path, line_number, col)
+SELECT 'field.' || i, 'fields.yml', i, 1 FROM n`)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if indexed {
+				if err = createIndexes(b.Context(), db); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			const query = `SELECT id FROM fields WHERE name = ?`
+			var id, parent, notUsed int64
+			var detail string
+			if err = db.QueryRowContext(b.Context(), "EXPLAIN QUERY PLAN "+query, "field.1").Scan(&id, &parent, &notUsed, &detail); err != nil {
+				b.Fatal(err)
+			}
+			if usesIndex := strings.Contains(detail, "idx_fields_name"); usesIndex != indexed {
+				b.Fatalf("unexpected query plan: %s", detail)
+			}
+
+			for b.Loop() {
+				if err = db.QueryRowContext(b.Context(), query, "field.25000").Scan(&id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// copiedPackages returns n copies of the test packages, each read from its
+// own uniquely named directory.
+func copiedPackages(t *testing.T, n int) []fleetpkg.Integration {
+	t.Helper()
+
+	dir := t.TempDir()
+	for i := range n {
+		dst := filepath.Join(dir, "packages", fmt.Sprintf("icons_%02d", i))
+		if err := os.CopyFS(dst, os.DirFS("testdata/integrations/packages/icons")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pkgs, err := loadPackages(slog.New(slog.DiscardHandler), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkgs
+}
+
+// writtenRowCounts writes pkgs to a new in-memory database using the given
+// number of workers and returns the number of rows in each table.
+func writtenRowCounts(t *testing.T, pkgs []fleetpkg.Integration, workers int) map[string]int64 {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", InMemoryDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = WritePackages(t.Context(), db, pkgs, WriteOptions{Workers: workers}); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[string]int64{}
+	for _, table := range database.TableNames {
+		var count int64
+		if err = db.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		counts[table] = count
+	}
+	return counts
+}
+
 func loadPackages(log *slog.Logger, integrationsDir string) ([]fleetpkg.Integration, error) {
 	// Load packages from disk.
 	packages, err := filepath.Glob(filepath.Join(integrationsDir, "packages/*"))
@@ -7,6 +7,10 @@ package fleetsql
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/andrewkroh/go-fleetpkg"
 )
@@ -22,7 +26,8 @@ type FlatProcessor struct {
 }
 
 // FlattenProcessors recursively flattens a list of processors, computing JSON Pointers
-// and merging on_failure handlers into the attributes.
+// and merging on_failure handlers into the attributes. The processor nested
+// inside a foreach processor is flattened too.
 func FlattenProcessors(processors []*fleetpkg.Processor, basePath string) ([]FlatProcessor, error) {
 	var result []FlatProcessor
 
@@ -34,45 +39,117 @@ func FlattenProcessors(processors []*fleetpkg.Processor, basePath string) ([]Fla
 		// Compute JSON Pointer for this processor
 		jsonPointer := fmt.Sprintf("%s/%d/%s", basePath, i, p.Type)
 
-		// Create a copy of attributes and add on_failure if present
-		attrs := make(map[string]any)
-		for k, v := range p.Attributes {
-			attrs[k] = v
+		flat, err := flattenProcessor(p, jsonPointer)
+		if err != nil {
+			return nil, err
 		}
+		result = append(result, flat...)
+	}
 
-		// Process on_failure handlers
-		if len(p.OnFailure) > 0 {
-			// Recursively flatten on_failure processors
-			onFailureFlat, err := FlattenProcessors(p.OnFailure, jsonPointer+"/on_failure")
+	return result, nil
+}
+
+// flattenProcessor flattens a single processor located at jsonPointer. Nested
+// processors are returned before the processor itself.
+func flattenProcessor(p *fleetpkg.Processor, jsonPointer string) ([]FlatProcessor, error) {
+	var result []FlatProcessor
+
+	// Create a copy of attributes and add on_failure if present
+	attrs := make(map[string]any)
+	for k, v := range p.Attributes {
+		attrs[k] = v
+	}
+
+	// Process the processor nested in a foreach.
+	if p.Type == "foreach" {
+		if m, ok := p.Attributes["processor"].(map[string]any); ok {
+			nested, err := processorFromMap(m, p.FileMetadata)
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("invalid foreach processor at %s: %w", jsonPointer, err)
 			}
-			result = append(result, onFailureFlat...)
-
-			// Marshal on_failure for this processor's attributes
-			onFailureJSON := make([]map[string]any, 0, len(p.OnFailure))
-			for _, of := range p.OnFailure {
-				procAttrs := make(map[string]any)
-				procAttrs[of.Type] = of.Attributes
-				onFailureJSON = append(onFailureJSON, procAttrs)
+			nestedFlat, err := flattenProcessor(nested, jsonPointer+"/processor/"+nested.Type)
+			if err != nil {
+				return nil, err
 			}
-			attrs["on_failure"] = onFailureJSON
+			result = append(result, nestedFlat...)
+		}
+	}
+
+	// Process on_failure handlers
+	if len(p.OnFailure) > 0 {
+		// Recursively flatten on_failure processors
+		onFailureFlat, err := FlattenProcessors(p.OnFailure, jsonPointer+"/on_failure")
+		if err != nil {
+			return nil, err
 		}
+		result = append(result, onFailureFlat...)
 
-		// Add this processor
-		result = append(result, FlatProcessor{
-			Type:        p.Type,
-			Attributes:  attrs,
-			JSONPointer: jsonPointer,
-			FilePath:    p.Path(),
-			Line:        p.Line(),
-			Column:      p.Column(),
-		})
+		// Marshal on_failure for this processor's attributes
+		onFailureJSON := make([]map[string]any, 0, len(p.OnFailure))
+		for _, of := range p.OnFailure {
+			procAttrs := make(map[string]any)
+			procAttrs[of.Type] = of.Attributes
+			onFailureJSON = append(onFailureJSON, procAttrs)
+		}
+		attrs["on_failure"] = onFailureJSON
 	}
 
+	// Add this processor
+	result = append(result, FlatProcessor{
+		Type:        p.Type,
+		Attributes:  attrs,
+		JSONPointer: jsonPointer,
+		FilePath:    p.Path(),
+		Line:        p.Line(),
+		Column:      p.Column(),
+	})
+
 	return result, nil
 }
 
+// processorFromMap converts a processor definition such as
+// {"set": {"field": "a", "value": "b"}} into a *fleetpkg.Processor. Nested
+// processors have no location of their own so the given file metadata of the
+// enclosing processor is used.
+func processorFromMap(m map[string]any, meta fleetpkg.FileMetadata) (*fleetpkg.Processor, error) {
+	if len(m) != 1 {
+		return nil, fmt.Errorf("processor must have exactly one key, got %d", len(m))
+	}
+
+	p := &fleetpkg.Processor{FileMetadata: meta}
+	for typ, v := range m {
+		p.Type = typ
+		attrs, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s processor attributes must be an object", typ)
+		}
+
+		p.Attributes = make(map[string]any, len(attrs))
+		for k, v := range attrs {
+			if k != "on_failure" {
+				p.Attributes[k] = v
+				continue
+			}
+			handlers, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s processor on_failure must be a list", typ)
+			}
+			for _, h := range handlers {
+				hm, ok := h.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("%s processor on_failure entries must be objects", typ)
+				}
+				of, err := processorFromMap(hm, meta)
+				if err != nil {
+					return nil, err
+				}
+				p.OnFailure = append(p.OnFailure, of)
+			}
+		}
+	}
+	return p, nil
+}
+
 // MarshalAttributes marshals the processor attributes to JSON.
 func (fp FlatProcessor) MarshalAttributes() (string, error) {
 	if len(fp.Attributes) == 0 {
@@ -84,3 +161,126 @@ func (fp FlatProcessor) MarshalAttributes() (string, error) {
 	}
 	return string(data), nil
 }
+
+// GrokPattern is a grok expression or custom pattern definition used by a
+// grok processor.
+type GrokPattern struct {
+	Field          string // Field parsed by the processor.
+	Pattern        string // Grok expression.
+	DefinitionName string // Name of the custom pattern. Empty for entries from 'patterns'.
+}
+
+// GrokPatterns returns the entries of the 'patterns' and 'pattern_definitions'
+// attributes of a grok processor. It returns nil for other processor types.
+func (fp FlatProcessor) GrokPatterns() []GrokPattern {
+	if fp.Type != "grok" {
+		return nil
+	}
+
+	field, _ := fp.Attributes["field"].(string)
+
+	var out []GrokPattern
+	if patterns, ok := fp.Attributes["patterns"].([]any); ok {
+		for _, p := range patterns {
+			if s, ok := p.(string); ok {
+				out = append(out, GrokPattern{Field: field, Pattern: s})
+			}
+		}
+	}
+	if defs, ok := fp.Attributes["pattern_definitions"].(map[string]any); ok {
+		for _, name := range slices.Sorted(maps.Keys(defs)) {
+			if s, ok := defs[name].(string); ok {
+				out = append(out, GrokPattern{Field: field, Pattern: s, DefinitionName: name})
+			}
+		}
+	}
+	return out
+}
+
+// DissectPattern is the pattern used by a dissect processor.
+type DissectPattern struct {
+	Field     string // Field parsed by the processor.
+	Tokenizer string // Dissect pattern.
+}
+
+// DissectPattern returns the 'pattern' attribute of a dissect processor. It
+// returns false for other processor types or when the pattern is missing.
+func (fp FlatProcessor) DissectPattern() (DissectPattern, bool) {
+	if fp.Type != "dissect" {
+		return DissectPattern{}, false
+	}
+
+	pattern, ok := fp.Attributes["pattern"].(string)
+	if !ok || pattern == "" {
+		return DissectPattern{}, false
+	}
+	field, _ := fp.Attributes["field"].(string)
+	return DissectPattern{Field: field, Tokenizer: pattern}, true
+}
+
+// FieldWrite is a field written by a processor.
+type FieldWrite struct {
+	Operation string // set, append, rename_from, or rename_to.
+	FieldName string
+}
+
+// FieldWrites returns the fields written by set, append, and rename
+// processors. It returns nil for other processor types.
+func (fp FlatProcessor) FieldWrites() []FieldWrite {
+	field, _ := fp.Attributes["field"].(string)
+
+	var out []FieldWrite
+	switch fp.Type {
+	case "set", "append":
+		if field != "" {
+			out = append(out, FieldWrite{Operation: fp.Type, FieldName: field})
+		}
+	case "rename":
+		if field != "" {
+			out = append(out, FieldWrite{Operation: "rename_from", FieldName: field})
+		}
+		if target, _ := fp.Attributes["target_field"].(string); target != "" {
+			out = append(out, FieldWrite{Operation: "rename_to", FieldName: target})
+		}
+	}
+	return out
+}
+
+// ingestPipelineTemplateRegex matches the '{{ IngestPipeline "name" }}'
+// template used by integrations to reference a pipeline of the same data
+// stream.
+var ingestPipelineTemplateRegex = regexp.MustCompile(`^\{\{\s*IngestPipeline\s+"([^"]+)"\s*\}\}$`)
+
+// PipelineReference returns the name of the pipeline called by a pipeline
+// processor. '{{ IngestPipeline "name" }}' templates are resolved to the
+// name. It returns false for other processor types or when the name is
+// missing.
+func (fp FlatProcessor) PipelineReference() (string, bool) {
+	if fp.Type != "pipeline" {
+		return "", false
+	}
+
+	name, _ := fp.Attributes["name"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", false
+	}
+	if m := ingestPipelineTemplateRegex.FindStringSubmatch(name); m != nil {
+		return m[1], true
+	}
+	return name, true
+}
+
+// Depth returns the number of on_failure handlers that enclose the processor,
+// as counted from its JSON Pointer. A top-level processor has depth 0 and a
+// processor inside the on_failure of another processor, or inside the
+// pipeline's global on_failure, has depth 1.
+func (fp FlatProcessor) Depth() int {
+	var depth int
+	for segment := range strings.SplitSeq(fp.JSONPointer, "/") {
+		if segment == "on_failure" {
+			depth++
+		}
+	}
+	return depth
+}
@@ -179,6 +179,54 @@ func TestFlattenProcessors(t *testing.T) {
 				assert.Equal(t, "/on_failure/0/set", result[0].JSONPointer)
 			},
 		},
+		{
+			name: "foreach processor",
+			input: []*fleetpkg.Processor{
+				{
+					Type: "set",
+					Attributes: map[string]any{
+						"field": "event.kind",
+						"value": "event",
+					},
+				},
+				{
+					Type: "foreach",
+					Attributes: map[string]any{
+						"field": "items",
+						"processor": map[string]any{
+							"convert": map[string]any{
+								"field": "_ingest._value.count",
+								"type":  "long",
+								"on_failure": []any{
+									map[string]any{
+										"remove": map[string]any{
+											"field": "_ingest._value.count",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			basePath:  "/processors",
+			wantCount: 4, // set + nested on_failure + nested processor + foreach
+			validate: func(t *testing.T, result []FlatProcessor) {
+				assert.Equal(t, "/processors/0/set", result[0].JSONPointer)
+
+				assert.Equal(t, "remove", result[1].Type)
+				assert.Equal(t, "/processors/1/foreach/processor/convert/on_failure/0/remove", result[1].JSONPointer)
+
+				assert.Equal(t, "convert", result[2].Type)
+				assert.Equal(t, "/processors/1/foreach/processor/convert", result[2].JSONPointer)
+				assert.Equal(t, "_ingest._value.count", result[2].Attributes["field"])
+				assert.Contains(t, result[2].Attributes, "on_failure")
+
+				assert.Equal(t, "foreach", result[3].Type)
+				assert.Equal(t, "/processors/1/foreach", result[3].JSONPointer)
+				assert.Contains(t, result[3].Attributes, "processor")
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -263,3 +311,160 @@ func TestFlatProcessor_MarshalAttributes(t *testing.T) {
 		})
 	}
 }
+
+func TestFlatProcessor_GrokPatterns(t *testing.T) {
+	tests := []struct {
+		name      string
+		processor FlatProcessor
+		want      []GrokPattern
+	}{
+		{
+			name: "patterns and definitions",
+			processor: FlatProcessor{
+				Type: "grok",
+				Attributes: map[string]any{
+					"field":    "message",
+					"patterns": []any{"%{IP:source.ip} %{NGINX_HOST}", "%{GREEDYDATA:message}"},
+					"pattern_definitions": map[string]any{
+						"NGINX_HOST": "%{IP}:%{POSINT}",
+						"A_FIRST":    "%{WORD}",
+					},
+				},
+			},
+			want: []GrokPattern{
+				{Field: "message", Pattern: "%{IP:source.ip} %{NGINX_HOST}"},
+				{Field: "message", Pattern: "%{GREEDYDATA:message}"},
+				{Field: "message", Pattern: "%{WORD}", DefinitionName: "A_FIRST"},
+				{Field: "message", Pattern: "%{IP}:%{POSINT}", DefinitionName: "NGINX_HOST"},
+			},
+		},
+		{
+			name: "not a grok processor",
+			processor: FlatProcessor{
+				Type: "dissect",
+				Attributes: map[string]any{
+					"field":    "message",
+					"patterns": []any{"%{a}"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.processor.GrokPatterns())
+		})
+	}
+}
+
+func TestFlatProcessor_DissectPattern(t *testing.T) {
+	dp, ok := FlatProcessor{
+		Type: "dissect",
+		Attributes: map[string]any{
+			"field":   "message",
+			"pattern": "%{source.ip} - %{user.name}",
+		},
+	}.DissectPattern()
+	require.True(t, ok)
+	assert.Equal(t, DissectPattern{Field: "message", Tokenizer: "%{source.ip} - %{user.name}"}, dp)
+
+	_, ok = FlatProcessor{Type: "dissect", Attributes: map[string]any{"field": "message"}}.DissectPattern()
+	assert.False(t, ok)
+
+	_, ok = FlatProcessor{Type: "grok", Attributes: map[string]any{"pattern": "%{a}"}}.DissectPattern()
+	assert.False(t, ok)
+}
+
+func TestFlatProcessor_FieldWrites(t *testing.T) {
+	tests := []struct {
+		name      string
+		processor FlatProcessor
+		want      []FieldWrite
+	}{
+		{
+			name:      "set",
+			processor: FlatProcessor{Type: "set", Attributes: map[string]any{"field": "ecs.version", "value": "8.11.0"}},
+			want:      []FieldWrite{{Operation: "set", FieldName: "ecs.version"}},
+		},
+		{
+			name:      "append",
+			processor: FlatProcessor{Type: "append", Attributes: map[string]any{"field": "related.ip", "value": "{{source.ip}}"}},
+			want:      []FieldWrite{{Operation: "append", FieldName: "related.ip"}},
+		},
+		{
+			name:      "rename",
+			processor: FlatProcessor{Type: "rename", Attributes: map[string]any{"field": "message", "target_field": "event.original"}},
+			want: []FieldWrite{
+				{Operation: "rename_from", FieldName: "message"},
+				{Operation: "rename_to", FieldName: "event.original"},
+			},
+		},
+		{
+			name:      "other processor",
+			processor: FlatProcessor{Type: "remove", Attributes: map[string]any{"field": "message"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.processor.FieldWrites())
+		})
+	}
+}
+
+func TestFlatProcessor_PipelineReference(t *testing.T) {
+	tests := []struct {
+		name      string
+		processor FlatProcessor
+		want      string
+		wantOK    bool
+	}{
+		{
+			name:      "template",
+			processor: FlatProcessor{Type: "pipeline", Attributes: map[string]any{"name": `{{ IngestPipeline "http" }}`}},
+			want:      "http",
+			wantOK:    true,
+		},
+		{
+			name:      "literal name",
+			processor: FlatProcessor{Type: "pipeline", Attributes: map[string]any{"name": "logs-nginx.access-1.0.0"}},
+			want:      "logs-nginx.access-1.0.0",
+			wantOK:    true,
+		},
+		{
+			name:      "missing name",
+			processor: FlatProcessor{Type: "pipeline", Attributes: map[string]any{}},
+		},
+		{
+			name:      "other processor",
+			processor: FlatProcessor{Type: "set", Attributes: map[string]any{"name": "x"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.processor.PipelineReference()
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFlatProcessor_Depth(t *testing.T) {
+	tests := []struct {
+		jsonPointer string
+		want        int
+	}{
+		{"/processors/0/set", 0},
+		{"/processors/2/foreach/processor/set", 0},
+		{"/processors/1/rename/on_failure/0/append", 1},
+		{"/on_failure/0/set", 1},
+		{"/processors/1/rename/on_failure/0/set/on_failure/0/append", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.jsonPointer, func(t *testing.T) {
+			assert.Equal(t, tt.want, FlatProcessor{JSONPointer: tt.jsonPointer}.Depth())
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package fleetsql
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// maxReadmeBytes is the maximum size of README content that is stored.
+const maxReadmeBytes = 500 * 1024
+
+// Readme is the docs/README.md file of a package.
+type Readme struct {
+	Content   string
+	Truncated bool // Content was truncated to maxReadmeBytes.
+	Path      string
+}
+
+// ReadReadme reads docs/README.md from the package directory. It returns
+// false if the file does not exist. Content longer than maxReadmeBytes is
+// truncated on a UTF-8 character boundary.
+func ReadReadme(packageDir string) (Readme, bool, error) {
+	path := filepath.Join(packageDir, "docs", "README.md")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Readme{}, false, nil
+		}
+		return Readme{}, false, err
+	}
+
+	r := Readme{Path: path}
+	if len(data) > maxReadmeBytes {
+		data = data[:maxReadmeBytes]
+		// Drop a partial multi-byte character left by the cut.
+		for len(data) > 0 {
+			if c, size := utf8.DecodeLastRune(data); c != utf8.RuneError || size > 1 {
+				break
+			}
+			data = data[:len(data)-1]
+		}
+		r.Truncated = true
+	}
+	r.Content = string(data)
+	return r, true, nil
+}
@@ -0,0 +1,57 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package fleetsql
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// SampleEventFieldPaths returns the sorted, unique dot-notation paths of the
+// leaf values in a JSON sample event (e.g. "event.dataset"). Objects nested
+// inside arrays contribute their keys under the path of the array.
+func SampleEventFieldPaths(eventJSON []byte) ([]string, error) {
+	var event any
+	if err := json.Unmarshal(eventJSON, &event); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]struct{}{}
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, child := range v {
+				if prefix != "" {
+					k = prefix + "." + k
+				}
+				walk(k, child)
+			}
+		case []any:
+			var hasObject bool
+			for _, elem := range v {
+				if m, ok := elem.(map[string]any); ok {
+					hasObject = true
+					walk(prefix, m)
+				}
+			}
+			if !hasObject && prefix != "" {
+				seen[prefix] = struct{}{}
+			}
+		default:
+			if prefix != "" {
+				seen[prefix] = struct{}{}
+			}
+		}
+	}
+	walk("", event)
+
+	paths := make([]string, 0, len(seen))
+	for p := range seen {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
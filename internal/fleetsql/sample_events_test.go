@@ -0,0 +1,36 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package fleetsql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleEventFieldPaths(t *testing.T) {
+	paths, err := SampleEventFieldPaths([]byte(`{
+		"@timestamp": "2024-01-01T00:00:00Z",
+		"event": {"dataset": "nginx.access", "category": ["web"]},
+		"source.ip": "10.0.0.1",
+		"threat": {"enrichments": [{"indicator": {"ip": "1.1.1.1"}}, {"matched": {"atomic": "x"}}]},
+		"empty": {},
+		"nothing": null
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"@timestamp",
+		"event.category",
+		"event.dataset",
+		"nothing",
+		"source.ip",
+		"threat.enrichments.indicator.ip",
+		"threat.enrichments.matched.atomic",
+	}, paths)
+
+	_, err = SampleEventFieldPaths([]byte(`{`))
+	assert.Error(t, err)
+}
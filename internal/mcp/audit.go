@@ -0,0 +1,88 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// AuditLog records every SQL query executed as one JSON object per line.
+// It is safe for concurrent use.
+type AuditLog struct {
+	mu sync.Mutex
+	f  *os.File
+	w  *bufio.Writer
+}
+
+// auditEntry is a single line in the audit log.
+type auditEntry struct {
+	Timestamp  time.Time `json:"ts"`
+	Statement  string    `json:"statement"`
+	Rows       int       `json:"rows"`
+	DurationMS int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// OpenAuditLog opens the file at path for appending, creating it if needed.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+// record appends an entry to the log.
+func (a *AuditLog) record(e auditEntry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.w.Write(line)
+	return err
+}
+
+// Close flushes any buffered entries and closes the file.
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return errors.Join(a.w.Flush(), a.f.Close())
+}
+
+// audit writes the outcome of a query to the audit log. Failures to write are
+// logged but do not affect the query result.
+func (t *tools) audit(ctx context.Context, statement string, rows int, elapsed time.Duration, res *mcp.CallToolResult, err error) {
+	e := auditEntry{
+		Timestamp:  time.Now().UTC(),
+		Statement:  statement,
+		Rows:       rows,
+		DurationMS: elapsed.Milliseconds(),
+	}
+	switch {
+	case err != nil:
+		e.Error = err.Error()
+	case res != nil && res.IsError && len(res.Content) > 0:
+		if text, ok := res.Content[0].(*mcp.TextContent); ok {
+			e.Error = text.Text
+		}
+	}
+
+	if err := t.opts.AuditLog.record(e); err != nil {
+		t.log.ErrorContext(ctx, "Failed to write audit log", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,222 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func addChangelogTools(s *mcp.Server, t *tools) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_find_releases_with_link",
+		Description: `Returns the releases of an integration along with the GitHub pull request
+and issue links referenced by the changes in each release. Useful for
+generating release notes cross-referenced with GitHub.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.findReleasesWithLink)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_changelog",
+		Description: `Returns the changelog of an integration as a JSON array of releases in the
+order they appear in changelog.yml (newest first). Each entry contains the
+version and its changes as [{description, type, link}].`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getChangelog)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_most_active_packages",
+		Description: `Ranks integrations by the number of changes recorded in their changelog.yml.
+Returns a JSON array of {name, change_count}, largest first. limit sets the number
+of integrations returned (default 20).`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getMostActivePackages)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_change_types",
+		Description: `Returns a JSON array of {change_type, count} for every distinct changelog change
+type, where count is the number of changes using it. Sorted by count, most used first.
+Useful for spotting non-standard or misspelled types (e.g. "bugfix" vs "bug fix").`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listChangeTypes)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_release_notes",
+		Description: `Returns the changes of one release of an integration as a JSON array of
+{description, type, link} in the order they appear in changelog.yml. If the
+integration has no release with the given version a message saying so is returned.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getReleaseNotes)
+}
+
+type FindReleasesWithLinkArgs struct {
+	IntegrationName string `json:"integration_name" jsonschema:"name of the integration package (e.g. aws)"`
+}
+
+const releasesWithLinkQuery = `
+SELECT releases.version                          AS version,
+       json_group_array(DISTINCT changes.link) AS links
+FROM integrations
+         JOIN changelogs ON changelogs.integration_id = integrations.id
+         JOIN releases ON releases.changelog_id = changelogs.id
+         JOIN changes ON changes.release_id = releases.id
+WHERE integrations.name = ?
+  AND changes.link IS NOT NULL
+  AND changes.link LIKE '%github.com%'
+GROUP BY releases.id
+ORDER BY releases.id`
+
+func (t *tools) findReleasesWithLink(ctx context.Context, req *mcp.CallToolRequest, args FindReleasesWithLinkArgs) (*mcp.CallToolResult, any, error) {
+	if args.IntegrationName == "" {
+		return mcpErrorf("integration_name is required"), nil, nil
+	}
+
+	rows, errResult := t.query(ctx, releasesWithLinkQuery, args.IntegrationName)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	rawJSONColumns(rows, "links")
+	return t.jsonResult(ctx, rows)
+}
+
+type GetChangelogArgs struct {
+	IntegrationName string `json:"integration_name" jsonschema:"name of the integration package (e.g. aws)"`
+}
+
+const changelogQuery = `
+SELECT releases.version AS version,
+       (SELECT json_group_array(json_object(
+                   'description', c.description,
+                   'type', c.type,
+                   'link', c.link))
+        FROM (SELECT * FROM changes WHERE changes.release_id = releases.id ORDER BY changes.id) AS c) AS changes
+FROM integrations
+         JOIN changelogs ON changelogs.integration_id = integrations.id
+         JOIN releases ON releases.changelog_id = changelogs.id
+WHERE integrations.name = ?
+ORDER BY releases.id`
+
+func (t *tools) getChangelog(ctx context.Context, req *mcp.CallToolRequest, args GetChangelogArgs) (*mcp.CallToolResult, any, error) {
+	if args.IntegrationName == "" {
+		return mcpErrorf("integration_name is required"), nil, nil
+	}
+
+	rows, errResult := t.query(ctx, changelogQuery, args.IntegrationName)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	rawJSONColumns(rows, "changes")
+	return t.jsonResult(ctx, rows)
+}
+
+type GetMostActivePackagesArgs struct {
+	Limit int `json:"limit,omitempty" jsonschema:"maximum number of integrations to return (default 20)"`
+}
+
+// defaultMostActivePackagesLimit is the number of integrations returned by
+// fleetpkg_get_most_active_packages when no limit is given.
+const defaultMostActivePackagesLimit = 20
+
+const mostActivePackagesQuery = `
+SELECT integrations.name, COUNT(changes.id) AS change_count
+FROM changes
+         JOIN releases ON releases.id = changes.release_id
+         JOIN changelogs ON changelogs.id = releases.changelog_id
+         JOIN integrations ON integrations.id = changelogs.integration_id
+GROUP BY integrations.name
+ORDER BY change_count DESC, integrations.name
+LIMIT ?`
+
+func (t *tools) getMostActivePackages(ctx context.Context, req *mcp.CallToolRequest, args GetMostActivePackagesArgs) (*mcp.CallToolResult, any, error) {
+	limit := args.Limit
+	if limit < 0 {
+		return mcpErrorf("limit must not be negative"), nil, nil
+	}
+	if limit == 0 {
+		limit = defaultMostActivePackagesLimit
+	}
+	return t.queryTool(ctx, mostActivePackagesQuery, limit)
+}
+
+const listChangeTypesQuery = `
+SELECT type AS change_type, COUNT(*) AS count
+FROM changes
+GROUP BY type
+ORDER BY count DESC, type`
+
+func (t *tools) listChangeTypes(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, listChangeTypesQuery)
+}
+
+type GetReleaseNotesArgs struct {
+	IntegrationName string `json:"integration_name" jsonschema:"name of the integration package (e.g. aws)"`
+	Version         string `json:"version" jsonschema:"version of the release (e.g. 1.2.0)"`
+}
+
+// releaseNotesQuery returns the changes of a release. A release without
+// changes yields one row with a NULL change_id so that it can be told apart
+// from a release that does not exist.
+const releaseNotesQuery = `
+SELECT changes.id          AS change_id,
+       changes.description AS description,
+       changes.type        AS type,
+       changes.link        AS link
+FROM integrations
+         JOIN changelogs ON changelogs.integration_id = integrations.id
+         JOIN releases ON releases.changelog_id = changelogs.id
+         LEFT JOIN changes ON changes.release_id = releases.id
+WHERE integrations.name = ?
+  AND releases.version = ?
+ORDER BY changes.id`
+
+func (t *tools) getReleaseNotes(ctx context.Context, req *mcp.CallToolRequest, args GetReleaseNotesArgs) (*mcp.CallToolResult, any, error) {
+	if args.IntegrationName == "" {
+		return mcpErrorf("integration_name is required"), nil, nil
+	}
+	if args.Version == "" {
+		return mcpErrorf("version is required"), nil, nil
+	}
+
+	// A single query keeps the release and its changes consistent when the
+	// database is reloaded concurrently.
+	rows, errResult := t.query(ctx, releaseNotesQuery, args.IntegrationName, args.Version)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	if len(rows) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("No release %q exists in the changelog of integration %q. Use fleetpkg_get_changelog to list its releases.", args.Version, args.IntegrationName)},
+			},
+		}, nil, nil
+	}
+
+	changes := rows[:0]
+	for _, row := range rows {
+		if row["change_id"] == nil {
+			continue
+		}
+		delete(row, "change_id")
+		changes = append(changes, row)
+	}
+	return t.jsonResult(ctx, changes)
+}
@@ -0,0 +1,151 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetChangelog(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		`INSERT INTO changelogs (id, integration_id, file_path) VALUES (1, 1, 'changelog.yml')`,
+		`INSERT INTO releases (id, changelog_id, version, file_path) VALUES
+			(1, 1, '1.1.0', 'changelog.yml'),
+			(2, 1, '1.0.0', 'changelog.yml')`,
+		`INSERT INTO changes (release_id, description, type, link, file_path) VALUES
+			(1, 'Add error logs.', 'enhancement', 'https://github.com/elastic/integrations/pull/2', 'changelog.yml'),
+			(1, 'Fix timestamp parsing.', 'bugfix', 'https://github.com/elastic/integrations/pull/3', 'changelog.yml'),
+			(2, 'Initial release.', 'enhancement', 'https://github.com/elastic/integrations/pull/1', 'changelog.yml')`,
+	)
+
+	res, _, err := tl.getChangelog(t.Context(), nil, GetChangelogArgs{IntegrationName: "nginx"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	type change struct {
+		Description string `json:"description"`
+		Type        string `json:"type"`
+		Link        string `json:"link"`
+	}
+	type release struct {
+		Version string   `json:"version"`
+		Changes []change `json:"changes"`
+	}
+	var got []release
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	assert.Equal(t, []release{
+		{
+			Version: "1.1.0",
+			Changes: []change{
+				{"Add error logs.", "enhancement", "https://github.com/elastic/integrations/pull/2"},
+				{"Fix timestamp parsing.", "bugfix", "https://github.com/elastic/integrations/pull/3"},
+			},
+		},
+		{
+			Version: "1.0.0",
+			Changes: []change{
+				{"Initial release.", "enhancement", "https://github.com/elastic/integrations/pull/1"},
+			},
+		},
+	}, got)
+}
+
+func TestGetMostActivePackages(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+		insertIntegrationSQL(3, "aws"),
+		`INSERT INTO changelogs (id, integration_id, file_path) VALUES
+			(1, 1, 'changelog.yml'),
+			(2, 2, 'changelog.yml'),
+			(3, 3, 'changelog.yml')`,
+		`INSERT INTO releases (id, changelog_id, version, file_path) VALUES
+			(1, 1, '1.1.0', 'changelog.yml'),
+			(2, 1, '1.0.0', 'changelog.yml'),
+			(3, 2, '1.0.0', 'changelog.yml'),
+			(4, 3, '1.0.0', 'changelog.yml')`,
+		`INSERT INTO changes (release_id, description, type, link, file_path) VALUES
+			(1, 'Add error logs.', 'enhancement', 'https://github.com/elastic/integrations/pull/2', 'changelog.yml'),
+			(2, 'Initial release.', 'enhancement', 'https://github.com/elastic/integrations/pull/1', 'changelog.yml'),
+			(2, 'Add dashboards.', 'enhancement', 'https://github.com/elastic/integrations/pull/3', 'changelog.yml'),
+			(3, 'Initial release.', 'enhancement', 'https://github.com/elastic/integrations/pull/4', 'changelog.yml'),
+			(4, 'Initial release.', 'enhancement', 'https://github.com/elastic/integrations/pull/5', 'changelog.yml')`,
+	)
+
+	res, _, err := tl.getMostActivePackages(t.Context(), nil, GetMostActivePackagesArgs{Limit: 2})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{"name":"nginx","change_count":3},{"name":"apache","change_count":1}]`, resultText(t, res))
+
+	res, _, err = tl.getMostActivePackages(t.Context(), nil, GetMostActivePackagesArgs{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.Contains(t, resultText(t, res), `"aws"`)
+}
+
+func TestListChangeTypes(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		`INSERT INTO changelogs (id, integration_id, file_path) VALUES (1, 1, 'changelog.yml')`,
+		`INSERT INTO releases (id, changelog_id, version, file_path) VALUES (1, 1, '1.0.0', 'changelog.yml')`,
+		`INSERT INTO changes (release_id, description, type, file_path) VALUES
+			(1, 'Add error logs.', 'enhancement', 'changelog.yml'),
+			(1, 'Add dashboards.', 'enhancement', 'changelog.yml'),
+			(1, 'Fix timestamp parsing.', 'bugfix', 'changelog.yml'),
+			(1, 'Fix grok pattern.', 'bug fix', 'changelog.yml')`,
+	)
+
+	res, _, err := tl.listChangeTypes(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"change_type":"enhancement","count":2},
+		{"change_type":"bug fix","count":1},
+		{"change_type":"bugfix","count":1}
+	]`, resultText(t, res))
+}
+
+func TestGetReleaseNotes(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		`INSERT INTO changelogs (id, integration_id, file_path) VALUES (1, 1, 'changelog.yml')`,
+		`INSERT INTO releases (id, changelog_id, version, file_path) VALUES
+			(1, 1, '1.1.0', 'changelog.yml'),
+			(2, 1, '1.0.0', 'changelog.yml'),
+			(3, 1, '0.9.0', 'changelog.yml')`,
+		`INSERT INTO changes (release_id, description, type, link, file_path) VALUES
+			(1, 'Add error logs.', 'enhancement', 'https://github.com/elastic/integrations/pull/2', 'changelog.yml'),
+			(1, 'Fix timestamp parsing.', 'bugfix', 'https://github.com/elastic/integrations/pull/3', 'changelog.yml'),
+			(2, 'Initial release.', 'enhancement', 'https://github.com/elastic/integrations/pull/1', 'changelog.yml')`,
+	)
+
+	res, _, err := tl.getReleaseNotes(t.Context(), nil, GetReleaseNotesArgs{IntegrationName: "nginx", Version: "1.1.0"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"description":"Add error logs.","type":"enhancement","link":"https://github.com/elastic/integrations/pull/2"},
+		{"description":"Fix timestamp parsing.","type":"bugfix","link":"https://github.com/elastic/integrations/pull/3"}
+	]`, resultText(t, res))
+
+	res, _, err = tl.getReleaseNotes(t.Context(), nil, GetReleaseNotesArgs{IntegrationName: "nginx", Version: "9.9.9"})
+	require.NoError(t, err)
+	require.False(t, res.IsError)
+	assert.Contains(t, resultText(t, res), `No release "9.9.9"`)
+
+	// A release without changes exists but has no notes.
+	res, _, err = tl.getReleaseNotes(t.Context(), nil, GetReleaseNotesArgs{IntegrationName: "nginx", Version: "0.9.0"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[]`, resultText(t, res))
+
+	res, _, err = tl.getReleaseNotes(t.Context(), nil, GetReleaseNotesArgs{IntegrationName: "nginx"})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
@@ -0,0 +1,297 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func addDataStreamTools(s *mcp.Server, t *tools) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_find_packages_with_elasticsearch_dynamic_dataset",
+		Description: `Returns the integration and data stream names of data streams that enable
+elasticsearch.dynamic_dataset. These data streams allow the dataset to be set
+dynamically at collection time rather than being fixed in the manifest.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.findDynamicDatasetDataStreams)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_data_streams",
+		Description: `Returns the data streams of the named integration with their name, type,
+title, dataset, ilm_policy, and field_count (number of field definitions).`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getDataStreams)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_sample_event",
+		Description: `Returns the sample_event.json of a data stream as raw JSON. This is an example
+of a document produced by the data stream after ingest pipeline processing.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getSampleEvent)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_input_types",
+		Description: `Returns a JSON array of {input_type, count} for every input type (e.g. logfile,
+aws-s3, httpjson) used by data stream streams, where count is the number of streams
+using it. Sorted by count, most used first.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listInputTypes)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_index_modes",
+		Description: `Returns a JSON array of {index_mode, count} for every Elasticsearch index mode
+(e.g. logsdb, time_series) set by data streams, where count is the number of data
+streams using it. Data streams without an index mode are excluded. Sorted by count,
+most used first. Useful for migration planning such as tracking logsdb adoption.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listIndexModes)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_custom_index_settings",
+		Description: `Returns the data streams that override the Elasticsearch index template settings
+or mappings as {integration_name, data_stream_name, has_custom_settings,
+has_custom_mappings, file_path}, where the has_ columns are 1 when the data stream
+manifest sets elasticsearch.index_template.settings or .mappings. Sorted by integration
+and data stream name. Use fleetpkg_get_data_streams to see the settings themselves.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getCustomIndexSettings)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_field_coverage",
+		Description: `Compares the declared fields of a data stream with the fields present in its
+sample event. Returns {missing_from_sample, undeclared_in_sample} where
+missing_from_sample lists declared fields (excluding groups) with no value in the
+sample event and undeclared_in_sample lists sample event fields with no declaration.
+Values beneath object, flattened, and nested fields count as declared.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getFieldCoverage)
+}
+
+const dynamicDatasetQuery = `
+SELECT integrations.name AS integration_name,
+       data_streams.name AS data_stream_name
+FROM data_streams
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE data_streams.elasticsearch_dynamic_dataset = 1
+ORDER BY integrations.name, data_streams.name`
+
+func (t *tools) findDynamicDatasetDataStreams(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, dynamicDatasetQuery)
+}
+
+type GetDataStreamsArgs struct {
+	Name string `json:"name" jsonschema:"name of the integration package (e.g. nginx)"`
+}
+
+const getDataStreamsQuery = `
+SELECT data_streams.name,
+       data_streams.type,
+       data_streams.title,
+       data_streams.dataset,
+       data_streams.ilm_policy,
+       (SELECT COUNT(*)
+        FROM fields
+                 JOIN data_stream_fields ON data_stream_fields.field_id = fields.id
+        WHERE data_stream_fields.data_stream_id = data_streams.id) AS field_count
+FROM data_streams
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE integrations.name = ?
+ORDER BY data_streams.name`
+
+func (t *tools) getDataStreams(ctx context.Context, req *mcp.CallToolRequest, args GetDataStreamsArgs) (*mcp.CallToolResult, any, error) {
+	if args.Name == "" {
+		return mcpErrorf("name is required"), nil, nil
+	}
+	return t.queryTool(ctx, getDataStreamsQuery, args.Name)
+}
+
+type GetSampleEventArgs struct {
+	IntegrationName string `json:"integration_name" jsonschema:"name of the integration package (e.g. nginx)"`
+	DataStreamName  string `json:"data_stream_name" jsonschema:"name of the data stream (e.g. access)"`
+}
+
+const sampleEventQuery = `
+SELECT sample_events.event
+FROM sample_events
+         JOIN data_streams ON data_streams.id = sample_events.data_stream_id
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE integrations.name = ?
+  AND data_streams.name = ?
+LIMIT 1`
+
+func (t *tools) getSampleEvent(ctx context.Context, req *mcp.CallToolRequest, args GetSampleEventArgs) (*mcp.CallToolResult, any, error) {
+	if args.IntegrationName == "" {
+		return mcpErrorf("integration_name is required"), nil, nil
+	}
+	if args.DataStreamName == "" {
+		return mcpErrorf("data_stream_name is required"), nil, nil
+	}
+
+	rows, errResult := t.query(ctx, sampleEventQuery, args.IntegrationName, args.DataStreamName)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	text := fmt.Sprintf("No sample event exists for data stream %q of integration %q.", args.DataStreamName, args.IntegrationName)
+	if len(rows) > 0 {
+		if event, ok := rows[0]["event"].(string); ok {
+			text = event
+		}
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+	}, nil, nil
+}
+
+const listInputTypesQuery = `
+SELECT input AS input_type, COUNT(*) AS count
+FROM streams
+GROUP BY input
+ORDER BY count DESC, input`
+
+func (t *tools) listInputTypes(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, listInputTypesQuery)
+}
+
+const listIndexModesQuery = `
+SELECT elasticsearch_index_mode AS index_mode, COUNT(*) AS count
+FROM data_streams
+WHERE elasticsearch_index_mode IS NOT NULL
+GROUP BY elasticsearch_index_mode
+ORDER BY count DESC, elasticsearch_index_mode`
+
+func (t *tools) listIndexModes(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, listIndexModesQuery)
+}
+
+const customIndexSettingsQuery = `
+SELECT integrations.name                                              AS integration_name,
+       data_streams.name                                              AS data_stream_name,
+       data_streams.elasticsearch_index_template_settings IS NOT NULL AS has_custom_settings,
+       data_streams.elasticsearch_index_template_mappings IS NOT NULL AS has_custom_mappings,
+       data_streams.file_path
+FROM data_streams
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE data_streams.elasticsearch_index_template_settings IS NOT NULL
+   OR data_streams.elasticsearch_index_template_mappings IS NOT NULL
+ORDER BY integrations.name, data_streams.name`
+
+func (t *tools) getCustomIndexSettings(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, customIndexSettingsQuery)
+}
+
+type GetFieldCoverageArgs struct {
+	IntegrationName string `json:"integration_name" jsonschema:"name of the integration package (e.g. nginx)"`
+	DataStreamName  string `json:"data_stream_name" jsonschema:"name of the data stream (e.g. access)"`
+}
+
+const (
+	// fieldCoverageCTE selects the declared fields and the sample event
+	// fields of one data stream.
+	fieldCoverageCTE = `
+WITH ds AS (SELECT data_streams.id
+            FROM data_streams
+                     JOIN integrations ON integrations.id = data_streams.integration_id
+            WHERE integrations.name = ?1
+              AND data_streams.name = ?2),
+     declared AS (SELECT DISTINCT fields.name, fields.type
+                  FROM data_stream_fields
+                           JOIN fields ON fields.id = data_stream_fields.field_id
+                  WHERE data_stream_fields.data_stream_id IN (SELECT id FROM ds)),
+     sampled AS (SELECT DISTINCT field_name
+                 FROM sample_event_fields
+                 WHERE data_stream_id IN (SELECT id FROM ds))`
+
+	sampleEventFieldCountQuery = fieldCoverageCTE + `
+SELECT COUNT(*) AS count FROM sampled`
+
+	missingFromSampleQuery = fieldCoverageCTE + `
+SELECT DISTINCT declared.name AS name
+FROM declared
+         LEFT JOIN sampled ON sampled.field_name = declared.name
+    OR substr(sampled.field_name, 1, length(declared.name) + 1) = declared.name || '.'
+WHERE sampled.field_name IS NULL
+  AND COALESCE(declared.type, '') <> 'group'
+ORDER BY declared.name`
+
+	undeclaredInSampleQuery = fieldCoverageCTE + `
+SELECT DISTINCT sampled.field_name AS name
+FROM sampled
+         LEFT JOIN declared ON declared.name = sampled.field_name
+    OR (declared.type IN ('object', 'flattened', 'nested')
+        AND substr(sampled.field_name, 1, length(declared.name) + 1) = declared.name || '.')
+WHERE declared.name IS NULL
+ORDER BY sampled.field_name`
+)
+
+// fieldCoverage is the response of fleetpkg_get_field_coverage.
+type fieldCoverage struct {
+	MissingFromSample  []string `json:"missing_from_sample"`
+	UndeclaredInSample []string `json:"undeclared_in_sample"`
+}
+
+func (t *tools) getFieldCoverage(ctx context.Context, req *mcp.CallToolRequest, args GetFieldCoverageArgs) (*mcp.CallToolResult, any, error) {
+	if args.IntegrationName == "" {
+		return mcpErrorf("integration_name is required"), nil, nil
+	}
+	if args.DataStreamName == "" {
+		return mcpErrorf("data_stream_name is required"), nil, nil
+	}
+
+	rows, errResult := t.query(ctx, sampleEventFieldCountQuery, args.IntegrationName, args.DataStreamName)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	if count, _ := rows[0]["count"].(int64); count == 0 {
+		return mcpErrorf("no sample event exists for data stream %q of integration %q", args.DataStreamName, args.IntegrationName), nil, nil
+	}
+
+	var result fieldCoverage
+	for _, q := range []struct {
+		statement string
+		dst       *[]string
+	}{
+		{missingFromSampleQuery, &result.MissingFromSample},
+		{undeclaredInSampleQuery, &result.UndeclaredInSample},
+	} {
+		rows, errResult := t.query(ctx, q.statement, args.IntegrationName, args.DataStreamName)
+		if errResult != nil {
+			return errResult, nil, nil
+		}
+		*q.dst = make([]string, 0, len(rows))
+		for _, row := range rows {
+			name, _ := row["name"].(string)
+			*q.dst = append(*q.dst, name)
+		}
+	}
+
+	return t.jsonResult(ctx, result)
+}
@@ -0,0 +1,159 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDataStreams(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 1, "error"),
+		insertFieldSQL(1, 1, "nginx.access.remote_ip", "ip"),
+		insertFieldSQL(2, 1, "nginx.access.method", "keyword"),
+		insertFieldSQL(3, 2, "nginx.error.message", "text"),
+		`UPDATE data_streams SET ilm_policy = 'logs-nginx.access-default_policy' WHERE id = 1`,
+	)
+
+	res, _, err := tl.getDataStreams(t.Context(), nil, GetDataStreamsArgs{Name: "nginx"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	var got []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	require.Len(t, got, 2)
+	assert.Equal(t, "access", got[0]["name"])
+	assert.Equal(t, "logs", got[0]["type"])
+	assert.Equal(t, "logs-nginx.access-default_policy", got[0]["ilm_policy"])
+	assert.EqualValues(t, 2, got[0]["field_count"])
+	assert.Equal(t, "error", got[1]["name"])
+	assert.EqualValues(t, 1, got[1]["field_count"])
+}
+
+func TestGetSampleEvent(t *testing.T) {
+	const event = `{"@timestamp":"2024-01-01T00:00:00.000Z","event":{"dataset":"nginx.access"}}`
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 1, "error"),
+		`INSERT INTO sample_events (data_stream_id, event, file_path)
+		 VALUES (1, '`+event+`', 'data_stream/access/sample_event.json')`,
+	)
+
+	res, _, err := tl.getSampleEvent(t.Context(), nil, GetSampleEventArgs{IntegrationName: "nginx", DataStreamName: "access"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, event, resultText(t, res))
+
+	res, _, err = tl.getSampleEvent(t.Context(), nil, GetSampleEventArgs{IntegrationName: "nginx", DataStreamName: "error"})
+	require.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.Contains(t, resultText(t, res), "No sample event")
+}
+
+func TestListInputTypes(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 1, "error"),
+		`INSERT INTO streams (data_stream_id, input, title, description) VALUES
+			(1, 'logfile', 'Access logs', 'Collect access logs'),
+			(2, 'logfile', 'Error logs', 'Collect error logs'),
+			(2, 'httpjson', 'Error logs API', 'Collect error logs via API')`,
+	)
+
+	res, _, err := tl.listInputTypes(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{"input_type":"logfile","count":2},{"input_type":"httpjson","count":1}]`, resultText(t, res))
+}
+
+func TestListIndexModes(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 1, "error"),
+		insertDataStreamSQL(3, 1, "stubstatus"),
+		insertDataStreamSQL(4, 1, "metrics"),
+		`UPDATE data_streams SET elasticsearch_index_mode = 'logsdb' WHERE id IN (1, 2)`,
+		`UPDATE data_streams SET elasticsearch_index_mode = 'time_series' WHERE id = 4`,
+	)
+
+	res, _, err := tl.listIndexModes(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{"index_mode":"logsdb","count":2},{"index_mode":"time_series","count":1}]`, resultText(t, res))
+}
+
+func TestGetCustomIndexSettings(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "aws"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 1, "error"),
+		insertDataStreamSQL(3, 2, "cloudtrail"),
+		`UPDATE data_streams SET elasticsearch_index_template_settings = '{"index":{"codec":"best_compression"}}' WHERE id = 1`,
+		`UPDATE data_streams SET elasticsearch_index_template_mappings = '{"dynamic":false}' WHERE id = 3`,
+	)
+
+	res, _, err := tl.getCustomIndexSettings(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	var got []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	require.Len(t, got, 2)
+
+	assert.Equal(t, "aws", got[0]["integration_name"])
+	assert.Equal(t, "cloudtrail", got[0]["data_stream_name"])
+	assert.EqualValues(t, 0, got[0]["has_custom_settings"])
+	assert.EqualValues(t, 1, got[0]["has_custom_mappings"])
+
+	assert.Equal(t, "nginx", got[1]["integration_name"])
+	assert.Equal(t, "access", got[1]["data_stream_name"])
+	assert.EqualValues(t, 1, got[1]["has_custom_settings"])
+	assert.EqualValues(t, 0, got[1]["has_custom_mappings"])
+	assert.NotEmpty(t, got[1]["file_path"])
+}
+
+func TestGetFieldCoverage(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 1, "error"),
+		insertFieldSQL(1, 1, "@timestamp", "date"),
+		insertFieldSQL(2, 1, "event.dataset", "constant_keyword"),
+		insertFieldSQL(3, 1, "nginx.access", "group"),
+		insertFieldSQL(4, 1, "nginx.access.method", "keyword"),
+		insertFieldSQL(5, 1, "nginx.access.labels", "flattened"),
+		`INSERT INTO sample_events (id, data_stream_id, event, file_path) VALUES (1, 1, '{}', 'sample_event.json')`,
+		`INSERT INTO sample_event_fields (sample_event_id, data_stream_id, field_name) VALUES
+			(1, 1, '@timestamp'),
+			(1, 1, 'nginx.access.labels.env'),
+			(1, 1, 'nginx.access.unknown')`,
+	)
+
+	res, _, err := tl.getFieldCoverage(t.Context(), nil, GetFieldCoverageArgs{IntegrationName: "nginx", DataStreamName: "access"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `{
+		"missing_from_sample": ["event.dataset", "nginx.access.method"],
+		"undeclared_in_sample": ["nginx.access.unknown"]
+	}`, resultText(t, res))
+
+	res, _, err = tl.getFieldCoverage(t.Context(), nil, GetFieldCoverageArgs{IntegrationName: "nginx", DataStreamName: "error"})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+
+	res, _, err = tl.getFieldCoverage(t.Context(), nil, GetFieldCoverageArgs{IntegrationName: "nginx"})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
@@ -0,0 +1,254 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func addFieldTools(s *mcp.Server, t *tools) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_find_packages_with_duplicate_field_definitions",
+		Description: `Returns fields that are defined more than once across different fields files
+within the same data stream of an integration. Duplicate definitions can cause
+unexpected behavior when fields are flattened.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.findDuplicateFieldDefinitions)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_field_types",
+		Description: `Returns a JSON array of {field_type, count} for every Elasticsearch field type
+(e.g. keyword, long, date) used in field definitions across all integrations.
+Sorted by count, most used first.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listFieldTypes)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_unresolvable_ecs_fields",
+		Description: `Returns fields declared with "external: ecs" that do not exist in the ECS
+version bundled with fleetpkg-mcp. These are usually stale references to renamed or
+removed ECS fields. Set integration_name to limit the results to one integration.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getUnresolvableECSFields)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_metric_fields",
+		Description: `Returns the TSDB dimension fields ("dimension: true") and metric fields (with a
+metric_type of gauge or counter) of data streams as a JSON array of
+{integration_name, data_stream_name, name, type, metric_type, dimension}.
+Set integration_name to limit the results to one integration.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getMetricFields)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_search_fields",
+		Description: `Full-text search over field names and descriptions. query uses SQLite FTS5
+syntax (e.g. "source address", "ip AND geo", or "user*"). Returns up to 100 field
+definitions as {name, type, description}, best matches first.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.searchFields)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_alias_fields",
+		Description: `Returns the alias type fields of data streams as a JSON array of
+{integration_name, data_stream_name, field_name, alias_target_path, target_exists}.
+target_exists is false when the alias points to a field that is not defined in the
+same data stream (a broken alias). Set integration_name to limit the results to one
+integration.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getAliasFields)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_array_fields",
+		Description: `Returns the fields declared with "normalize: [array]" as a JSON array of
+{integration_name, data_stream_name, field_name, type}. These fields are expected to
+hold multiple values, following ECS array semantics. Set integration_name to limit
+the results to one integration.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listArrayFields)
+}
+
+type FindDuplicateFieldDefinitionsArgs struct {
+	IntegrationName string `json:"integration_name" jsonschema:"name of the integration package (e.g. aws)"`
+}
+
+const duplicateFieldDefinitionsQuery = `
+SELECT data_streams.name                                  AS data_stream_name,
+       fields.name                                        AS field_name,
+       COUNT(DISTINCT data_stream_fields.fields_file_name) AS definition_count,
+       json_group_array(DISTINCT data_stream_fields.fields_file_name) AS fields_files
+FROM integrations
+         JOIN data_streams ON data_streams.integration_id = integrations.id
+         JOIN data_stream_fields ON data_stream_fields.data_stream_id = data_streams.id
+         JOIN fields ON fields.id = data_stream_fields.field_id
+WHERE integrations.name = ?
+GROUP BY data_streams.id, fields.name
+HAVING COUNT(DISTINCT data_stream_fields.fields_file_name) > 1
+ORDER BY data_streams.name, fields.name`
+
+func (t *tools) findDuplicateFieldDefinitions(ctx context.Context, req *mcp.CallToolRequest, args FindDuplicateFieldDefinitionsArgs) (*mcp.CallToolResult, any, error) {
+	if args.IntegrationName == "" {
+		return mcpErrorf("integration_name is required"), nil, nil
+	}
+
+	rows, errResult := t.query(ctx, duplicateFieldDefinitionsQuery, args.IntegrationName)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	rawJSONColumns(rows, "fields_files")
+	return t.jsonResult(ctx, rows)
+}
+
+const listFieldTypesQuery = `
+SELECT type AS field_type, COUNT(*) AS count
+FROM fields
+WHERE type IS NOT NULL
+GROUP BY type
+ORDER BY count DESC, type`
+
+func (t *tools) listFieldTypes(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, listFieldTypesQuery)
+}
+
+type GetUnresolvableECSFieldsArgs struct {
+	IntegrationName string `json:"integration_name,omitempty" jsonschema:"optional name of an integration package to filter by (e.g. aws)"`
+}
+
+const unresolvableECSFieldsQuery = `
+SELECT integrations.name                   AS integration_name,
+       data_streams.name                   AS data_stream_name,
+       fields.name                         AS field_name,
+       data_stream_fields.fields_file_name AS fields_file
+FROM fields
+         JOIN data_stream_fields ON data_stream_fields.field_id = fields.id
+         JOIN data_streams ON data_streams.id = data_stream_fields.data_stream_id
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE fields.unresolvable = 1
+  AND (?1 = '' OR integrations.name = ?1)
+ORDER BY integrations.name, data_streams.name, fields.name`
+
+func (t *tools) getUnresolvableECSFields(ctx context.Context, req *mcp.CallToolRequest, args GetUnresolvableECSFieldsArgs) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, unresolvableECSFieldsQuery, args.IntegrationName)
+}
+
+type GetMetricFieldsArgs struct {
+	IntegrationName string `json:"integration_name,omitempty" jsonschema:"optional name of an integration package to filter by (e.g. aws)"`
+}
+
+const metricFieldsQuery = `
+SELECT integrations.name  AS integration_name,
+       data_streams.name  AS data_stream_name,
+       fields.name        AS name,
+       fields.type        AS type,
+       fields.metric_type AS metric_type,
+       fields.dimension   AS dimension
+FROM fields
+         JOIN data_stream_fields ON data_stream_fields.field_id = fields.id
+         JOIN data_streams ON data_streams.id = data_stream_fields.data_stream_id
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE (fields.metric_type IS NOT NULL OR fields.dimension = 1)
+  AND (?1 = '' OR integrations.name = ?1)
+ORDER BY integrations.name, data_streams.name, fields.name`
+
+func (t *tools) getMetricFields(ctx context.Context, req *mcp.CallToolRequest, args GetMetricFieldsArgs) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, metricFieldsQuery, args.IntegrationName)
+}
+
+type SearchFieldsArgs struct {
+	Query string `json:"query" jsonschema:"FTS5 full-text query matched against field names and descriptions"`
+}
+
+const searchFieldsQuery = `
+SELECT f.name, f.type, f.description
+FROM fields_fts
+         JOIN fields f ON fields_fts.rowid = f.id
+WHERE fields_fts MATCH ?
+ORDER BY fields_fts.rank
+LIMIT 100`
+
+func (t *tools) searchFields(ctx context.Context, req *mcp.CallToolRequest, args SearchFieldsArgs) (*mcp.CallToolResult, any, error) {
+	if args.Query == "" {
+		return mcpErrorf("query is required"), nil, nil
+	}
+	return t.queryTool(ctx, searchFieldsQuery, args.Query)
+}
+
+type GetAliasFieldsArgs struct {
+	IntegrationName string `json:"integration_name,omitempty" jsonschema:"optional name of an integration package to filter by (e.g. aws)"`
+}
+
+const aliasFieldsQuery = `
+SELECT DISTINCT integrations.name        AS integration_name,
+                data_streams.name        AS data_stream_name,
+                fields.name              AS field_name,
+                fields.alias_target_path AS alias_target_path,
+                json(CASE
+                         WHEN EXISTS (SELECT 1
+                                      FROM data_stream_fields AS target_dsf
+                                               JOIN fields AS target ON target.id = target_dsf.field_id
+                                      WHERE target_dsf.data_stream_id = data_streams.id
+                                        AND target.name = fields.alias_target_path) THEN 'true'
+                         ELSE 'false' END) AS target_exists
+FROM fields
+         JOIN data_stream_fields ON data_stream_fields.field_id = fields.id
+         JOIN data_streams ON data_streams.id = data_stream_fields.data_stream_id
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE fields.type = 'alias'
+  AND fields.alias_target_path IS NOT NULL
+  AND (?1 = '' OR integrations.name = ?1)
+ORDER BY integrations.name, data_streams.name, fields.name`
+
+func (t *tools) getAliasFields(ctx context.Context, req *mcp.CallToolRequest, args GetAliasFieldsArgs) (*mcp.CallToolResult, any, error) {
+	rows, errResult := t.query(ctx, aliasFieldsQuery, args.IntegrationName)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	rawJSONColumns(rows, "target_exists")
+	return t.jsonResult(ctx, rows)
+}
+
+type ListArrayFieldsArgs struct {
+	IntegrationName string `json:"integration_name,omitempty" jsonschema:"optional name of an integration package to filter by (e.g. aws)"`
+}
+
+const arrayFieldsQuery = `
+SELECT DISTINCT integrations.name AS integration_name,
+                data_streams.name AS data_stream_name,
+                fields.name       AS field_name,
+                fields.type       AS type
+FROM fields
+         JOIN data_stream_fields ON data_stream_fields.field_id = fields.id
+         JOIN data_streams ON data_streams.id = data_stream_fields.data_stream_id
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE fields.normalize LIKE '%array%'
+  AND (?1 = '' OR integrations.name = ?1)
+ORDER BY integrations.name, data_streams.name, fields.name`
+
+func (t *tools) listArrayFields(ctx context.Context, req *mcp.CallToolRequest, args ListArrayFieldsArgs) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, arrayFieldsQuery, args.IntegrationName)
+}
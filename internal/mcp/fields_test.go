@@ -0,0 +1,158 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFieldTypes(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertFieldSQL(1, 1, "nginx.access.method", "keyword"),
+		insertFieldSQL(2, 1, "nginx.access.user_agent", "keyword"),
+		insertFieldSQL(3, 1, "nginx.access.bytes", "long"),
+		insertFieldSQL(4, 1, "nginx.access.group", "group"),
+		`UPDATE fields SET type = NULL WHERE id = 4`,
+	)
+
+	res, _, err := tl.listFieldTypes(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{"field_type":"keyword","count":2},{"field_type":"long","count":1}]`, resultText(t, res))
+}
+
+func TestGetUnresolvableECSFields(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 2, "error"),
+		insertFieldSQL(1, 1, "source.ip", "ip"),
+		insertFieldSQL(2, 1, "host.legacy_id", "keyword"),
+		insertFieldSQL(3, 2, "log.legacy_level", "keyword"),
+		`UPDATE fields SET external = 'ecs' WHERE id IN (1, 2, 3)`,
+		`UPDATE fields SET unresolvable = 1 WHERE id IN (2, 3)`,
+	)
+
+	res, _, err := tl.getUnresolvableECSFields(t.Context(), nil, GetUnresolvableECSFieldsArgs{IntegrationName: "nginx"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{
+		"integration_name": "nginx",
+		"data_stream_name": "access",
+		"field_name": "host.legacy_id",
+		"fields_file": "fields.yml"
+	}]`, resultText(t, res))
+
+	res, _, err = tl.getUnresolvableECSFields(t.Context(), nil, GetUnresolvableECSFieldsArgs{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.Contains(t, resultText(t, res), "log.legacy_level")
+	assert.Contains(t, resultText(t, res), "host.legacy_id")
+}
+
+func TestGetMetricFields(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "aws"),
+		insertDataStreamSQL(1, 1, "ec2_metrics"),
+		insertFieldSQL(1, 1, "aws.ec2.cpu.total.pct", "scaled_float"),
+		insertFieldSQL(2, 1, "aws.ec2.instance.id", "keyword"),
+		insertFieldSQL(3, 1, "aws.ec2.instance.state.name", "keyword"),
+		`UPDATE fields SET metric_type = 'gauge' WHERE id = 1`,
+		`UPDATE fields SET dimension = 1 WHERE id = 2`,
+	)
+
+	res, _, err := tl.getMetricFields(t.Context(), nil, GetMetricFieldsArgs{IntegrationName: "aws"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"integration_name":"aws","data_stream_name":"ec2_metrics","name":"aws.ec2.cpu.total.pct",
+		 "type":"scaled_float","metric_type":"gauge","dimension":null},
+		{"integration_name":"aws","data_stream_name":"ec2_metrics","name":"aws.ec2.instance.id",
+		 "type":"keyword","metric_type":null,"dimension":1}
+	]`, resultText(t, res))
+}
+
+func TestSearchFields(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertFieldSQL(1, 1, "source.address", "keyword"),
+		insertFieldSQL(2, 1, "nginx.access.bytes", "long"),
+		`UPDATE fields SET description = 'Source network address.' WHERE id = 1`,
+		`UPDATE fields SET description = 'Number of bytes sent to the client.' WHERE id = 2`,
+		`INSERT INTO fields_fts (rowid, name, description) SELECT id, name, description FROM fields`,
+	)
+
+	res, _, err := tl.searchFields(t.Context(), nil, SearchFieldsArgs{Query: "network address"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{"name":"source.address","type":"keyword","description":"Source network address."}]`, resultText(t, res))
+
+	res, _, err = tl.searchFields(t.Context(), nil, SearchFieldsArgs{Query: "client"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{"name":"nginx.access.bytes","type":"long","description":"Number of bytes sent to the client."}]`, resultText(t, res))
+
+	res, _, err = tl.searchFields(t.Context(), nil, SearchFieldsArgs{})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
+
+func TestGetAliasFields(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 2, "error"),
+		insertFieldSQL(1, 1, "source.ip", "ip"),
+		insertFieldSQL(2, 1, "nginx.access.remote_ip", "alias"),
+		insertFieldSQL(3, 1, "nginx.access.agent", "alias"),
+		insertFieldSQL(4, 2, "apache.error.client_ip", "alias"),
+		`UPDATE fields SET alias_target_path = 'source.ip' WHERE id IN (2, 4)`,
+		`UPDATE fields SET alias_target_path = 'user_agent.original' WHERE id = 3`,
+	)
+
+	res, _, err := tl.getAliasFields(t.Context(), nil, GetAliasFieldsArgs{IntegrationName: "nginx"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"integration_name":"nginx","data_stream_name":"access","field_name":"nginx.access.agent",
+		 "alias_target_path":"user_agent.original","target_exists":false},
+		{"integration_name":"nginx","data_stream_name":"access","field_name":"nginx.access.remote_ip",
+		 "alias_target_path":"source.ip","target_exists":true}
+	]`, resultText(t, res))
+
+	// source.ip is only defined in the nginx data stream.
+	res, _, err = tl.getAliasFields(t.Context(), nil, GetAliasFieldsArgs{IntegrationName: "apache"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"integration_name":"apache","data_stream_name":"error","field_name":"apache.error.client_ip",
+		 "alias_target_path":"source.ip","target_exists":false}
+	]`, resultText(t, res))
+}
+
+func TestListArrayFields(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertFieldSQL(1, 1, "related.ip", "ip"),
+		insertFieldSQL(2, 1, "source.ip", "ip"),
+		`UPDATE fields SET normalize = '["array"]' WHERE id = 1`,
+	)
+
+	res, _, err := tl.listArrayFields(t.Context(), nil, ListArrayFieldsArgs{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"integration_name":"nginx","data_stream_name":"access","field_name":"related.ip","type":"ip"}
+	]`, resultText(t, res))
+}
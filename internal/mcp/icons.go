@@ -0,0 +1,91 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func addIconTools(s *mcp.Server, t *tools) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_validate_icon_dimensions",
+		Description: `Returns the integration and policy template icons whose pixel dimensions do not
+match any of the allowed sizes (default [[32,32],[64,64]]) as
+{integration_name, policy_template_name, src, width, height}. policy_template_name is
+null for integration icons. Icons in formats without fixed dimensions, such as SVG,
+are not checked.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.validateIconDimensions)
+}
+
+type ValidateIconDimensionsArgs struct {
+	AllowedSizes [][]int `json:"allowed_sizes,omitempty" jsonschema:"allowed [width, height] pairs in pixels, defaults to [[32,32],[64,64]]"`
+}
+
+// defaultIconSizes are the icon dimensions recommended by the Elastic
+// integrations style guide.
+var defaultIconSizes = [][]int{{32, 32}, {64, 64}}
+
+const iconDimensionsQuery = `
+SELECT integrations.name               AS integration_name,
+       NULL                            AS policy_template_name,
+       integration_icons.src           AS src,
+       integration_icons.icon_width    AS width,
+       integration_icons.icon_height   AS height
+FROM integration_icons
+         JOIN integrations ON integrations.id = integration_icons.integration_id
+WHERE integration_icons.icon_width IS NOT NULL
+UNION ALL
+SELECT integrations.name                 AS integration_name,
+       policy_templates.name             AS policy_template_name,
+       policy_template_icons.src         AS src,
+       policy_template_icons.icon_width  AS width,
+       policy_template_icons.icon_height AS height
+FROM policy_template_icons
+         JOIN policy_templates ON policy_templates.id = policy_template_icons.policy_template_id
+         JOIN integrations ON integrations.id = policy_templates.integration_id
+WHERE policy_template_icons.icon_width IS NOT NULL
+ORDER BY integration_name, policy_template_name, src`
+
+func (t *tools) validateIconDimensions(ctx context.Context, req *mcp.CallToolRequest, args ValidateIconDimensionsArgs) (*mcp.CallToolResult, any, error) {
+	allowed := args.AllowedSizes
+	if len(allowed) == 0 {
+		allowed = defaultIconSizes
+	}
+	for _, size := range allowed {
+		if len(size) != 2 {
+			return mcpErrorf("allowed_sizes entries must be [width, height] pairs, got %v", size), nil, nil
+		}
+	}
+
+	rows, errResult := t.query(ctx, iconDimensionsQuery)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	invalid := make([]map[string]any, 0)
+	for _, row := range rows {
+		width, _ := row["width"].(int64)
+		height, _ := row["height"].(int64)
+		if !iconSizeAllowed(allowed, width, height) {
+			invalid = append(invalid, row)
+		}
+	}
+	return t.jsonResult(ctx, invalid)
+}
+
+func iconSizeAllowed(allowed [][]int, width, height int64) bool {
+	for _, size := range allowed {
+		if int64(size[0]) == width && int64(size[1]) == height {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateIconDimensions(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		`INSERT INTO policy_templates (id, integration_id, name, title, description) VALUES (1, 1, 'nginx', 'Nginx', 'Nginx logs')`,
+		`INSERT INTO integration_icons (integration_id, src, icon_width, icon_height, icon_byte_size) VALUES
+			(1, '/img/logo.png', 32, 32, 100),
+			(1, '/img/large.png', 100, 80, 2000),
+			(1, '/img/logo.svg', NULL, NULL, NULL)`,
+		`INSERT INTO policy_template_icons (policy_template_id, src, icon_width, icon_height, icon_byte_size) VALUES
+			(1, '/img/pt.png', 48, 48, 300)`,
+	)
+
+	res, _, err := tl.validateIconDimensions(t.Context(), nil, ValidateIconDimensionsArgs{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"integration_name":"nginx","policy_template_name":null,"src":"/img/large.png","width":100,"height":80},
+		{"integration_name":"nginx","policy_template_name":"nginx","src":"/img/pt.png","width":48,"height":48}
+	]`, resultText(t, res))
+
+	res, _, err = tl.validateIconDimensions(t.Context(), nil, ValidateIconDimensionsArgs{
+		AllowedSizes: [][]int{{32, 32}, {48, 48}, {100, 80}},
+	})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[]`, resultText(t, res))
+
+	res, _, err = tl.validateIconDimensions(t.Context(), nil, ValidateIconDimensionsArgs{AllowedSizes: [][]int{{32}}})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
@@ -0,0 +1,495 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"context"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func addIngestPipelineTools(s *mcp.Server, t *tools) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_ingest_pipeline",
+		Description: `Returns an ingest pipeline of an integration as
+{name, description, version, file_path, data_stream_name, processors: [{type, json_pointer, attributes, line_number}]}.
+Processors, including on_failure handlers, are listed in the order they appear in the
+pipeline file. Set data_stream_name when several data streams have a pipeline with the
+same name (e.g. default).`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getIngestPipeline)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_processor_type_usage",
+		Description: `Returns a JSON array of {processor_type, count} for every ingest processor type
+(e.g. set, rename, grok) used in ingest pipelines, sorted by count, most used first.
+Set integration_name to only count processors from that integration.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getProcessorTypeUsage)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_search_by_processor_type",
+		Description: `Returns every usage of an ingest processor type (e.g. grok, script) as
+{integration_name, data_stream_name, pipeline_name, json_pointer, file_path, line_number}.
+Useful for auditing how a processor is used across all integrations.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.searchByProcessorType)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_deep_processor_chains",
+		Description: `Returns the ingest processors nested inside two or more on_failure handlers as
+{integration_name, data_stream_name, pipeline_name, processor_type, json_pointer, depth,
+file_path, line_number}, where depth is the number of enclosing on_failure handlers.
+Deep error handling chains are candidates for simplification. Sorted by depth, deepest
+first.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getDeepProcessorChains)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_grok_patterns",
+		Description: `Returns the unique grok expressions used by grok processors, grouped by
+integration, as [{integration_name, patterns: [...]}]. Set integration_name to limit the
+results to one integration. Custom patterns from pattern_definitions are stored in the
+grok_patterns table with a non-null definition_name.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listGrokPatterns)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_dissect_patterns",
+		Description: `Returns the unique patterns (tokenizers) used by dissect processors and the
+integrations that use each one, as [{tokenizer, integrations: [...]}], sorted by
+tokenizer.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listDissectPatterns)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_fields_set_by_processors",
+		Description: `Returns the fields written by a set, append, or rename processor type as
+[{field_name, operation, count}] where operation is set, append, rename_from, or
+rename_to and count is the number of processors writing the field. Set integration_name
+to limit the results to one integration.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getFieldsSetByProcessors)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_pipelines_using_field",
+		Description: `Returns every ingest processor that reads or writes a field, as
+[{integration_name, pipeline_name, processor_type, json_pointer}]. A processor matches
+when its field, target_field, or copy_from attribute is the field, or when it writes
+the field (set, append, rename). Use this for impact analysis before renaming a field.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getPipelinesUsingField)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_pipeline_dependencies",
+		Description: `Returns the graph of pipeline processor calls between the ingest pipelines of an
+integration as {pipelines: [{data_stream_name, pipeline_name, calls, unresolved}], cycles,
+depth_limit_exceeded}. calls lists the names of the pipelines of the same data stream
+that are called; unresolved lists called names with no matching pipeline. cycles lists
+call loops as "data_stream/pipeline" paths. Call chains are followed to a depth of 10;
+chains that go deeper are reported in depth_limit_exceeded.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getPipelineDependencies)
+}
+
+type GetIngestPipelineArgs struct {
+	IntegrationName string `json:"integration_name" jsonschema:"name of the integration package (e.g. nginx)"`
+	PipelineName    string `json:"pipeline_name" jsonschema:"name of the ingest pipeline file without extension (e.g. default)"`
+	DataStreamName  string `json:"data_stream_name,omitempty" jsonschema:"optional name of the data stream containing the pipeline"`
+}
+
+const (
+	ingestPipelineQuery = `
+SELECT ingest_pipelines.id,
+       ingest_pipelines.name,
+       ingest_pipelines.description,
+       ingest_pipelines.version,
+       ingest_pipelines.file_path,
+       data_streams.name AS data_stream_name
+FROM ingest_pipelines
+         JOIN data_streams ON data_streams.id = ingest_pipelines.data_stream_id
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE integrations.name = ?1
+  AND ingest_pipelines.name = ?2
+  AND (?3 = '' OR data_streams.name = ?3)
+ORDER BY data_streams.name`
+
+	// Processors are inserted in document order. json_pointer is not used for
+	// ordering because it does not sort numerically (/processors/10 < /processors/2).
+	ingestProcessorsQuery = `
+SELECT type, json_pointer, attributes, line_number
+FROM ingest_processors
+WHERE ingest_pipeline_id = ?
+ORDER BY id`
+)
+
+func (t *tools) getIngestPipeline(ctx context.Context, req *mcp.CallToolRequest, args GetIngestPipelineArgs) (*mcp.CallToolResult, any, error) {
+	if args.IntegrationName == "" {
+		return mcpErrorf("integration_name is required"), nil, nil
+	}
+	if args.PipelineName == "" {
+		return mcpErrorf("pipeline_name is required"), nil, nil
+	}
+
+	pipelines, errResult := t.query(ctx, ingestPipelineQuery, args.IntegrationName, args.PipelineName, args.DataStreamName)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	switch len(pipelines) {
+	case 0:
+		return mcpErrorf("ingest pipeline %q not found in integration %q", args.PipelineName, args.IntegrationName), nil, nil
+	case 1:
+	default:
+		names := make([]string, 0, len(pipelines))
+		for _, p := range pipelines {
+			names = append(names, p["data_stream_name"].(string))
+		}
+		return mcpErrorf("ingest pipeline %q exists in multiple data streams (%s), set data_stream_name",
+			args.PipelineName, strings.Join(names, ", ")), nil, nil
+	}
+	pipeline := pipelines[0]
+
+	processors, errResult := t.query(ctx, ingestProcessorsQuery, pipeline["id"])
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	rawJSONColumns(processors, "attributes")
+
+	delete(pipeline, "id")
+	pipeline["processors"] = processors
+	return t.jsonResult(ctx, pipeline)
+}
+
+type GetProcessorTypeUsageArgs struct {
+	IntegrationName string `json:"integration_name,omitempty" jsonschema:"optional name of an integration package to filter by (e.g. nginx)"`
+}
+
+const processorTypeUsageQuery = `
+SELECT ingest_processors.type AS processor_type, COUNT(*) AS count
+FROM ingest_processors
+         JOIN ingest_pipelines ON ingest_pipelines.id = ingest_processors.ingest_pipeline_id
+         JOIN data_streams ON data_streams.id = ingest_pipelines.data_stream_id
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE ?1 = '' OR integrations.name = ?1
+GROUP BY ingest_processors.type
+ORDER BY count DESC, ingest_processors.type`
+
+func (t *tools) getProcessorTypeUsage(ctx context.Context, req *mcp.CallToolRequest, args GetProcessorTypeUsageArgs) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, processorTypeUsageQuery, args.IntegrationName)
+}
+
+type SearchByProcessorTypeArgs struct {
+	ProcessorType string `json:"processor_type" jsonschema:"ingest processor type (e.g. grok)"`
+}
+
+const searchByProcessorTypeQuery = `
+SELECT integrations.name              AS integration_name,
+       data_streams.name              AS data_stream_name,
+       ingest_pipelines.name          AS pipeline_name,
+       ingest_processors.json_pointer AS json_pointer,
+       ingest_processors.file_path    AS file_path,
+       ingest_processors.line_number  AS line_number
+FROM ingest_processors
+         JOIN ingest_pipelines ON ingest_pipelines.id = ingest_processors.ingest_pipeline_id
+         JOIN data_streams ON data_streams.id = ingest_pipelines.data_stream_id
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE ingest_processors.type = ?
+ORDER BY integrations.name, data_streams.name, ingest_pipelines.name, ingest_processors.id`
+
+func (t *tools) searchByProcessorType(ctx context.Context, req *mcp.CallToolRequest, args SearchByProcessorTypeArgs) (*mcp.CallToolResult, any, error) {
+	if args.ProcessorType == "" {
+		return mcpErrorf("processor_type is required"), nil, nil
+	}
+	return t.queryTool(ctx, searchByProcessorTypeQuery, args.ProcessorType)
+}
+
+const deepProcessorChainsQuery = `
+SELECT integrations.name              AS integration_name,
+       data_streams.name              AS data_stream_name,
+       ingest_pipelines.name          AS pipeline_name,
+       ingest_processors.type         AS processor_type,
+       ingest_processors.json_pointer AS json_pointer,
+       ingest_processors.depth        AS depth,
+       ingest_processors.file_path    AS file_path,
+       ingest_processors.line_number  AS line_number
+FROM ingest_processors
+         JOIN ingest_pipelines ON ingest_pipelines.id = ingest_processors.ingest_pipeline_id
+         JOIN data_streams ON data_streams.id = ingest_pipelines.data_stream_id
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE ingest_processors.depth >= 2
+ORDER BY ingest_processors.depth DESC, integrations.name, data_streams.name, ingest_pipelines.name,
+         ingest_processors.id`
+
+func (t *tools) getDeepProcessorChains(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, deepProcessorChainsQuery)
+}
+
+type ListGrokPatternsArgs struct {
+	IntegrationName string `json:"integration_name,omitempty" jsonschema:"optional name of the integration package (e.g. nginx)"`
+}
+
+const listGrokPatternsQuery = `
+SELECT integration_name, json_group_array(pattern) AS patterns
+FROM (SELECT DISTINCT integrations.name AS integration_name, grok_patterns.pattern AS pattern
+      FROM grok_patterns
+               JOIN integrations ON integrations.id = grok_patterns.integration_id
+      WHERE grok_patterns.definition_name IS NULL
+        AND (?1 = '' OR integrations.name = ?1)
+      ORDER BY integration_name, pattern)
+GROUP BY integration_name
+ORDER BY integration_name`
+
+func (t *tools) listGrokPatterns(ctx context.Context, req *mcp.CallToolRequest, args ListGrokPatternsArgs) (*mcp.CallToolResult, any, error) {
+	rows, errResult := t.query(ctx, listGrokPatternsQuery, args.IntegrationName)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	rawJSONColumns(rows, "patterns")
+	return t.jsonResult(ctx, rows)
+}
+
+const listDissectPatternsQuery = `
+SELECT tokenizer, json_group_array(integration_name) AS integrations
+FROM (SELECT DISTINCT dissect_patterns.tokenizer AS tokenizer, integrations.name AS integration_name
+      FROM dissect_patterns
+               JOIN integrations ON integrations.id = dissect_patterns.integration_id
+      ORDER BY tokenizer, integration_name)
+GROUP BY tokenizer
+ORDER BY tokenizer`
+
+func (t *tools) listDissectPatterns(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	rows, errResult := t.query(ctx, listDissectPatternsQuery)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	rawJSONColumns(rows, "integrations")
+	return t.jsonResult(ctx, rows)
+}
+
+type GetFieldsSetByProcessorsArgs struct {
+	ProcessorType   string `json:"processor_type" jsonschema:"ingest processor type (set, append, or rename)"`
+	IntegrationName string `json:"integration_name,omitempty" jsonschema:"optional name of the integration package (e.g. nginx)"`
+}
+
+const fieldsSetByProcessorsQuery = `
+SELECT processor_field_writes.field_name AS field_name,
+       processor_field_writes.operation  AS operation,
+       COUNT(*)                          AS count
+FROM processor_field_writes
+         JOIN ingest_processors ON ingest_processors.id = processor_field_writes.ingest_processor_id
+         JOIN ingest_pipelines ON ingest_pipelines.id = ingest_processors.ingest_pipeline_id
+         JOIN data_streams ON data_streams.id = ingest_pipelines.data_stream_id
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE ingest_processors.type = ?1
+  AND (?2 = '' OR integrations.name = ?2)
+GROUP BY processor_field_writes.field_name, processor_field_writes.operation
+ORDER BY processor_field_writes.field_name, processor_field_writes.operation`
+
+func (t *tools) getFieldsSetByProcessors(ctx context.Context, req *mcp.CallToolRequest, args GetFieldsSetByProcessorsArgs) (*mcp.CallToolResult, any, error) {
+	if args.ProcessorType == "" {
+		return mcpErrorf("processor_type is required"), nil, nil
+	}
+	return t.queryTool(ctx, fieldsSetByProcessorsQuery, args.ProcessorType, args.IntegrationName)
+}
+
+type GetPipelinesUsingFieldArgs struct {
+	FieldName string `json:"field_name" jsonschema:"name of the field (e.g. source.ip)"`
+}
+
+const pipelinesUsingFieldQuery = `
+SELECT integrations.name              AS integration_name,
+       ingest_pipelines.name          AS pipeline_name,
+       ingest_processors.type         AS processor_type,
+       ingest_processors.json_pointer AS json_pointer
+FROM ingest_processors
+         JOIN ingest_pipelines ON ingest_pipelines.id = ingest_processors.ingest_pipeline_id
+         JOIN data_streams ON data_streams.id = ingest_pipelines.data_stream_id
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE json_extract(ingest_processors.attributes, '$.field') = ?1
+   OR json_extract(ingest_processors.attributes, '$.target_field') = ?1
+   OR json_extract(ingest_processors.attributes, '$.copy_from') = ?1
+   OR ingest_processors.id IN (SELECT ingest_processor_id FROM processor_field_writes WHERE field_name = ?1)
+ORDER BY integrations.name, data_streams.name, ingest_pipelines.name, ingest_processors.id`
+
+func (t *tools) getPipelinesUsingField(ctx context.Context, req *mcp.CallToolRequest, args GetPipelinesUsingFieldArgs) (*mcp.CallToolResult, any, error) {
+	if args.FieldName == "" {
+		return mcpErrorf("field_name is required"), nil, nil
+	}
+	return t.queryTool(ctx, pipelinesUsingFieldQuery, args.FieldName)
+}
+
+type GetPipelineDependenciesArgs struct {
+	IntegrationName string `json:"integration_name" jsonschema:"name of the integration package (e.g. nginx)"`
+}
+
+const (
+	integrationPipelinesQuery = `
+SELECT data_streams.name     AS data_stream_name,
+       ingest_pipelines.name AS pipeline_name
+FROM ingest_pipelines
+         JOIN data_streams ON data_streams.id = ingest_pipelines.data_stream_id
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE integrations.name = ?
+ORDER BY data_streams.name, ingest_pipelines.name`
+
+	pipelineReferencesQuery = `
+SELECT data_streams.name                            AS data_stream_name,
+       ingest_pipelines.name                        AS pipeline_name,
+       pipeline_references.referenced_pipeline_name AS referenced_pipeline_name
+FROM pipeline_references
+         JOIN ingest_pipelines ON ingest_pipelines.id = pipeline_references.source_pipeline_id
+         JOIN data_streams ON data_streams.id = ingest_pipelines.data_stream_id
+         JOIN integrations ON integrations.id = data_streams.integration_id
+WHERE integrations.name = ?
+ORDER BY pipeline_references.id`
+)
+
+// maxPipelineDepth is the deepest chain of pipeline processor calls that is
+// followed when searching for cycles.
+const maxPipelineDepth = 10
+
+// pipelineDependencies is the response of fleetpkg_get_pipeline_dependencies.
+type pipelineDependencies struct {
+	Pipelines          []*pipelineNode `json:"pipelines"`
+	Cycles             [][]string      `json:"cycles"`
+	DepthLimitExceeded []string        `json:"depth_limit_exceeded"`
+}
+
+type pipelineNode struct {
+	DataStreamName string   `json:"data_stream_name"`
+	PipelineName   string   `json:"pipeline_name"`
+	Calls          []string `json:"calls"`
+	Unresolved     []string `json:"unresolved"`
+
+	id    string          // data_stream/pipeline name without extension.
+	edges []*pipelineNode // Resolved calls.
+}
+
+func (t *tools) getPipelineDependencies(ctx context.Context, req *mcp.CallToolRequest, args GetPipelineDependenciesArgs) (*mcp.CallToolResult, any, error) {
+	if args.IntegrationName == "" {
+		return mcpErrorf("integration_name is required"), nil, nil
+	}
+
+	pipelines, errResult := t.query(ctx, integrationPipelinesQuery, args.IntegrationName)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	refs, errResult := t.query(ctx, pipelineReferencesQuery, args.IntegrationName)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	result := pipelineDependencies{
+		Pipelines:          []*pipelineNode{},
+		Cycles:             [][]string{},
+		DepthLimitExceeded: []string{},
+	}
+	nodes := map[string]*pipelineNode{}
+	for _, row := range pipelines {
+		ds, _ := row["data_stream_name"].(string)
+		name, _ := row["pipeline_name"].(string)
+		n := &pipelineNode{
+			DataStreamName: ds,
+			PipelineName:   name,
+			Calls:          []string{},
+			Unresolved:     []string{},
+			id:             pipelineNodeID(ds, strings.TrimSuffix(name, path.Ext(name))),
+		}
+		nodes[n.id] = n
+		result.Pipelines = append(result.Pipelines, n)
+	}
+	for _, row := range refs {
+		ds, _ := row["data_stream_name"].(string)
+		name, _ := row["pipeline_name"].(string)
+		ref, _ := row["referenced_pipeline_name"].(string)
+
+		n := nodes[pipelineNodeID(ds, strings.TrimSuffix(name, path.Ext(name)))]
+		if n == nil {
+			continue
+		}
+		if target := nodes[pipelineNodeID(ds, ref)]; target != nil {
+			n.Calls = append(n.Calls, ref)
+			n.edges = append(n.edges, target)
+		} else {
+			n.Unresolved = append(n.Unresolved, ref)
+		}
+	}
+
+	seenCycles := map[string]bool{}
+	seenDeep := map[string]bool{}
+	var stack []*pipelineNode
+	var walk func(n *pipelineNode)
+	walk = func(n *pipelineNode) {
+		for i, p := range stack {
+			if p == n {
+				cycle := make([]string, 0, len(stack)-i+1)
+				for _, c := range stack[i:] {
+					cycle = append(cycle, c.id)
+				}
+				cycle = append(cycle, n.id)
+				key := strings.Join(slices.Sorted(slices.Values(cycle[:len(cycle)-1])), ",")
+				if !seenCycles[key] {
+					seenCycles[key] = true
+					result.Cycles = append(result.Cycles, cycle)
+				}
+				return
+			}
+		}
+		if len(stack) == maxPipelineDepth {
+			if root := stack[0].id; !seenDeep[root] {
+				seenDeep[root] = true
+				result.DepthLimitExceeded = append(result.DepthLimitExceeded, root)
+			}
+			return
+		}
+
+		stack = append(stack, n)
+		for _, e := range n.edges {
+			walk(e)
+		}
+		stack = stack[:len(stack)-1]
+	}
+	for _, n := range result.Pipelines {
+		walk(n)
+	}
+
+	return t.jsonResult(ctx, result)
+}
+
+// pipelineNodeID identifies a pipeline within an integration by its data
+// stream and the name it is referenced by (its file name without extension).
+func pipelineNodeID(dataStream, pipeline string) string {
+	return dataStream + "/" + pipeline
+}
@@ -0,0 +1,353 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetIngestPipeline(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 1, "error"),
+		`INSERT INTO ingest_pipelines (id, data_stream_id, name, description, version, file_path) VALUES
+			(1, 1, 'default', 'Pipeline for nginx access logs', 1, 'data_stream/access/elasticsearch/ingest_pipeline/default.yml'),
+			(2, 2, 'default', 'Pipeline for nginx error logs', 1, 'data_stream/error/elasticsearch/ingest_pipeline/default.yml')`,
+		`INSERT INTO ingest_processors (ingest_pipeline_id, type, attributes, json_pointer, file_path, line_number, col) VALUES
+			(1, 'set', '{"field":"ecs.version","value":"8.11.0"}', '/processors/0/set', 'default.yml', 4, 5),
+			(1, 'grok', '{"field":"message","patterns":["%{IP:source.ip}"]}', '/processors/1/grok', 'default.yml', 8, 5)`,
+	)
+
+	t.Run("found", func(t *testing.T) {
+		res, _, err := tl.getIngestPipeline(t.Context(), nil, GetIngestPipelineArgs{
+			IntegrationName: "nginx",
+			PipelineName:    "default",
+			DataStreamName:  "access",
+		})
+		require.NoError(t, err)
+		require.False(t, res.IsError, resultText(t, res))
+
+		var got struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			Processors  []struct {
+				Type        string         `json:"type"`
+				JSONPointer string         `json:"json_pointer"`
+				Attributes  map[string]any `json:"attributes"`
+				LineNumber  int            `json:"line_number"`
+			} `json:"processors"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+		assert.Equal(t, "default", got.Name)
+		assert.Equal(t, "Pipeline for nginx access logs", got.Description)
+		require.Len(t, got.Processors, 2)
+		assert.Equal(t, "set", got.Processors[0].Type)
+		assert.Equal(t, "/processors/0/set", got.Processors[0].JSONPointer)
+		assert.Equal(t, "ecs.version", got.Processors[0].Attributes["field"])
+		assert.Equal(t, "grok", got.Processors[1].Type)
+		assert.Equal(t, 8, got.Processors[1].LineNumber)
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		res, _, err := tl.getIngestPipeline(t.Context(), nil, GetIngestPipelineArgs{
+			IntegrationName: "nginx",
+			PipelineName:    "default",
+		})
+		require.NoError(t, err)
+		assert.True(t, res.IsError)
+		assert.Contains(t, resultText(t, res), "access, error")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		res, _, err := tl.getIngestPipeline(t.Context(), nil, GetIngestPipelineArgs{
+			IntegrationName: "nginx",
+			PipelineName:    "missing",
+		})
+		require.NoError(t, err)
+		assert.True(t, res.IsError)
+	})
+}
+
+func TestGetProcessorTypeUsage(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 2, "access"),
+		`INSERT INTO ingest_pipelines (id, data_stream_id, name, file_path) VALUES
+			(1, 1, 'default', 'default.yml'),
+			(2, 2, 'default', 'default.yml')`,
+		`INSERT INTO ingest_processors (ingest_pipeline_id, type, json_pointer, file_path, line_number, col) VALUES
+			(1, 'set', '/processors/0/set', 'default.yml', 1, 1),
+			(1, 'set', '/processors/1/set', 'default.yml', 2, 1),
+			(1, 'grok', '/processors/2/grok', 'default.yml', 3, 1),
+			(2, 'rename', '/processors/0/rename', 'default.yml', 1, 1)`,
+	)
+
+	res, _, err := tl.getProcessorTypeUsage(t.Context(), nil, GetProcessorTypeUsageArgs{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"processor_type":"set","count":2},
+		{"processor_type":"grok","count":1},
+		{"processor_type":"rename","count":1}
+	]`, resultText(t, res))
+
+	res, _, err = tl.getProcessorTypeUsage(t.Context(), nil, GetProcessorTypeUsageArgs{IntegrationName: "apache"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{"processor_type":"rename","count":1}]`, resultText(t, res))
+}
+
+func TestSearchByProcessorType(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		`INSERT INTO ingest_pipelines (id, data_stream_id, name, file_path) VALUES (1, 1, 'default', 'default.yml')`,
+		`INSERT INTO ingest_processors (ingest_pipeline_id, type, json_pointer, file_path, line_number, col) VALUES
+			(1, 'set', '/processors/0/set', 'default.yml', 3, 5),
+			(1, 'script', '/processors/1/script', 'default.yml', 7, 5)`,
+	)
+
+	res, _, err := tl.searchByProcessorType(t.Context(), nil, SearchByProcessorTypeArgs{ProcessorType: "script"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{
+		"integration_name": "nginx",
+		"data_stream_name": "access",
+		"pipeline_name": "default",
+		"json_pointer": "/processors/1/script",
+		"file_path": "default.yml",
+		"line_number": 7
+	}]`, resultText(t, res))
+}
+
+func TestGetDeepProcessorChains(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		`INSERT INTO ingest_pipelines (id, data_stream_id, name, file_path) VALUES (1, 1, 'default', 'default.yml')`,
+		`INSERT INTO ingest_processors (ingest_pipeline_id, type, json_pointer, depth, file_path, line_number, col) VALUES
+			(1, 'set', '/processors/0/rename/on_failure/0/set/on_failure/0/set', 2, 'default.yml', 9, 13),
+			(1, 'append', '/processors/0/rename/on_failure/0/append', 1, 'default.yml', 6, 9),
+			(1, 'rename', '/processors/0/rename', 0, 'default.yml', 3, 5)`,
+	)
+
+	res, _, err := tl.getDeepProcessorChains(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{
+		"integration_name": "nginx",
+		"data_stream_name": "access",
+		"pipeline_name": "default",
+		"processor_type": "set",
+		"json_pointer": "/processors/0/rename/on_failure/0/set/on_failure/0/set",
+		"depth": 2,
+		"file_path": "default.yml",
+		"line_number": 9
+	}]`, resultText(t, res))
+}
+
+func TestListGrokPatterns(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 2, "error"),
+		`INSERT INTO ingest_pipelines (id, data_stream_id, name, file_path) VALUES
+			(1, 1, 'default', 'default.yml'),
+			(2, 2, 'default', 'default.yml')`,
+		`INSERT INTO ingest_processors (id, ingest_pipeline_id, type, attributes, json_pointer, file_path, line_number, col) VALUES
+			(1, 1, 'grok', '{}', '/processors/0/grok', 'default.yml', 4, 5),
+			(2, 1, 'grok', '{}', '/processors/1/grok', 'default.yml', 9, 5),
+			(3, 2, 'grok', '{}', '/processors/0/grok', 'default.yml', 4, 5)`,
+		`INSERT INTO grok_patterns (integration_id, ingest_processor_id, pipeline_name, field, pattern, definition_name) VALUES
+			(1, 1, 'default', 'message', '%{IP:source.ip} %{GREEDYDATA:message}', NULL),
+			(1, 1, 'default', 'message', '%{NGINX_HOST}', NULL),
+			(1, 1, 'default', 'message', '%{IP}:%{POSINT}', 'NGINX_HOST'),
+			(1, 2, 'default', 'message', '%{NGINX_HOST}', NULL),
+			(2, 3, 'default', 'message', '\[%{LOGLEVEL:log.level}\]', NULL)`,
+	)
+
+	res, _, err := tl.listGrokPatterns(t.Context(), nil, ListGrokPatternsArgs{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"integration_name":"apache","patterns":["\\[%{LOGLEVEL:log.level}\\]"]},
+		{"integration_name":"nginx","patterns":["%{IP:source.ip} %{GREEDYDATA:message}","%{NGINX_HOST}"]}
+	]`, resultText(t, res))
+
+	res, _, err = tl.listGrokPatterns(t.Context(), nil, ListGrokPatternsArgs{IntegrationName: "apache"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{"integration_name":"apache","patterns":["\\[%{LOGLEVEL:log.level}\\]"]}]`, resultText(t, res))
+}
+
+func TestListDissectPatterns(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 2, "error"),
+		`INSERT INTO ingest_pipelines (id, data_stream_id, name, file_path) VALUES
+			(1, 1, 'default', 'default.yml'),
+			(2, 2, 'default', 'default.yml')`,
+		`INSERT INTO ingest_processors (id, ingest_pipeline_id, type, attributes, json_pointer, file_path, line_number, col) VALUES
+			(1, 1, 'dissect', '{}', '/processors/0/dissect', 'default.yml', 4, 5),
+			(2, 1, 'dissect', '{}', '/processors/1/dissect', 'default.yml', 9, 5),
+			(3, 2, 'dissect', '{}', '/processors/0/dissect', 'default.yml', 4, 5)`,
+		`INSERT INTO dissect_patterns (integration_id, ingest_processor_id, pipeline_name, field, tokenizer) VALUES
+			(1, 1, 'default', 'message', '%{source.ip} - %{user.name}'),
+			(1, 2, 'default', 'message', '%{source.ip} - %{user.name}'),
+			(1, 2, 'default', 'event.original', '[%{log.level}] %{message}'),
+			(2, 3, 'default', 'message', '[%{log.level}] %{message}')`,
+	)
+
+	res, _, err := tl.listDissectPatterns(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"tokenizer":"%{source.ip} - %{user.name}","integrations":["nginx"]},
+		{"tokenizer":"[%{log.level}] %{message}","integrations":["apache","nginx"]}
+	]`, resultText(t, res))
+}
+
+func TestGetFieldsSetByProcessors(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 2, "error"),
+		`INSERT INTO ingest_pipelines (id, data_stream_id, name, file_path) VALUES
+			(1, 1, 'default', 'default.yml'),
+			(2, 2, 'default', 'default.yml')`,
+		`INSERT INTO ingest_processors (id, ingest_pipeline_id, type, attributes, json_pointer, file_path, line_number, col) VALUES
+			(1, 1, 'set', '{}', '/processors/0/set', 'default.yml', 4, 5),
+			(2, 1, 'rename', '{}', '/processors/1/rename', 'default.yml', 9, 5),
+			(3, 2, 'set', '{}', '/processors/0/set', 'default.yml', 4, 5)`,
+		`INSERT INTO processor_field_writes (ingest_processor_id, operation, field_name) VALUES
+			(1, 'set', 'ecs.version'),
+			(2, 'rename_from', 'message'),
+			(2, 'rename_to', 'event.original'),
+			(3, 'set', 'ecs.version')`,
+	)
+
+	res, _, err := tl.getFieldsSetByProcessors(t.Context(), nil, GetFieldsSetByProcessorsArgs{ProcessorType: "set"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{"field_name":"ecs.version","operation":"set","count":2}]`, resultText(t, res))
+
+	res, _, err = tl.getFieldsSetByProcessors(t.Context(), nil, GetFieldsSetByProcessorsArgs{
+		ProcessorType:   "rename",
+		IntegrationName: "nginx",
+	})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"field_name":"event.original","operation":"rename_to","count":1},
+		{"field_name":"message","operation":"rename_from","count":1}
+	]`, resultText(t, res))
+
+	res, _, err = tl.getFieldsSetByProcessors(t.Context(), nil, GetFieldsSetByProcessorsArgs{})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
+
+func TestGetPipelinesUsingField(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		`INSERT INTO ingest_pipelines (id, data_stream_id, name, file_path) VALUES (1, 1, 'default', 'default.yml')`,
+		`INSERT INTO ingest_processors (id, ingest_pipeline_id, type, attributes, json_pointer, file_path, line_number, col) VALUES
+			(1, 1, 'convert', '{"field":"source.port","type":"long"}', '/processors/0/convert', 'default.yml', 4, 5),
+			(2, 1, 'rename', '{"field":"src_ip","target_field":"source.ip"}', '/processors/1/rename', 'default.yml', 8, 5),
+			(3, 1, 'set', '{"field":"related.ip","copy_from":"source.ip"}', '/processors/2/set', 'default.yml', 12, 5),
+			(4, 1, 'geoip', '{"field":"source.ip","target_field":"source.geo"}', '/processors/3/geoip', 'default.yml', 16, 5),
+			(5, 1, 'remove', '{"field":"message"}', '/processors/4/remove', 'default.yml', 20, 5)`,
+	)
+
+	res, _, err := tl.getPipelinesUsingField(t.Context(), nil, GetPipelinesUsingFieldArgs{FieldName: "source.ip"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"integration_name":"nginx","pipeline_name":"default","processor_type":"rename","json_pointer":"/processors/1/rename"},
+		{"integration_name":"nginx","pipeline_name":"default","processor_type":"set","json_pointer":"/processors/2/set"},
+		{"integration_name":"nginx","pipeline_name":"default","processor_type":"geoip","json_pointer":"/processors/3/geoip"}
+	]`, resultText(t, res))
+
+	res, _, err = tl.getPipelinesUsingField(t.Context(), nil, GetPipelinesUsingFieldArgs{})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
+
+func TestGetPipelineDependencies(t *testing.T) {
+	seed := []string{
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		insertDataStreamSQL(2, 1, "error"),
+		`INSERT INTO ingest_pipelines (id, data_stream_id, name, file_path) VALUES
+			(1, 1, 'default.yml', 'default.yml'),
+			(2, 1, 'http.yml', 'http.yml'),
+			(3, 1, 'geo.yml', 'geo.yml')`,
+		`INSERT INTO ingest_processors (id, ingest_pipeline_id, type, json_pointer, file_path, line_number, col) VALUES
+			(1, 1, 'pipeline', '/processors/0/pipeline', 'default.yml', 1, 1),
+			(2, 2, 'pipeline', '/processors/0/pipeline', 'http.yml', 1, 1),
+			(3, 2, 'pipeline', '/processors/1/pipeline', 'http.yml', 2, 1),
+			(4, 3, 'pipeline', '/processors/0/pipeline', 'geo.yml', 1, 1)`,
+		`INSERT INTO pipeline_references (source_pipeline_id, ingest_processor_id, referenced_pipeline_name) VALUES
+			(1, 1, 'http'),
+			(2, 2, 'geo'),
+			(2, 3, 'missing'),
+			(3, 4, 'http')`,
+	}
+	// A chain of 12 pipelines in the error data stream: p00 -> p01 -> ... -> p11.
+	for i := range 12 {
+		seed = append(seed,
+			fmt.Sprintf(`INSERT INTO ingest_pipelines (id, data_stream_id, name, file_path) VALUES (%d, 2, 'p%02d.yml', 'p%02d.yml')`, 100+i, i, i))
+		if i < 11 {
+			seed = append(seed,
+				fmt.Sprintf(`INSERT INTO ingest_processors (id, ingest_pipeline_id, type, json_pointer, file_path, line_number, col) VALUES (%d, %d, 'pipeline', '/processors/0/pipeline', 'p.yml', 1, 1)`, 100+i, 100+i),
+				fmt.Sprintf(`INSERT INTO pipeline_references (source_pipeline_id, ingest_processor_id, referenced_pipeline_name) VALUES (%d, %d, 'p%02d')`, 100+i, 100+i, i+1))
+		}
+	}
+	tl := newTestTools(t, Options{}, seed...)
+
+	res, _, err := tl.getPipelineDependencies(t.Context(), nil, GetPipelineDependenciesArgs{IntegrationName: "nginx"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	var got struct {
+		Pipelines []struct {
+			DataStreamName string   `json:"data_stream_name"`
+			PipelineName   string   `json:"pipeline_name"`
+			Calls          []string `json:"calls"`
+			Unresolved     []string `json:"unresolved"`
+		} `json:"pipelines"`
+		Cycles             [][]string `json:"cycles"`
+		DepthLimitExceeded []string   `json:"depth_limit_exceeded"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+
+	require.Len(t, got.Pipelines, 15)
+	assert.Equal(t, "access", got.Pipelines[0].DataStreamName)
+	assert.Equal(t, "default.yml", got.Pipelines[0].PipelineName)
+	assert.Equal(t, []string{"http"}, got.Pipelines[0].Calls)
+	assert.Equal(t, "http.yml", got.Pipelines[2].PipelineName)
+	assert.Equal(t, []string{"geo"}, got.Pipelines[2].Calls)
+	assert.Equal(t, []string{"missing"}, got.Pipelines[2].Unresolved)
+
+	assert.Equal(t, [][]string{{"access/http", "access/geo", "access/http"}}, got.Cycles)
+	assert.Equal(t, []string{"error/p00", "error/p01"}, got.DepthLimitExceeded)
+
+	res, _, err = tl.getPipelineDependencies(t.Context(), nil, GetPipelineDependenciesArgs{})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
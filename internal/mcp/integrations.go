@@ -0,0 +1,548 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func addIntegrationTools(s *mcp.Server, t *tools) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_all_distinct_categories",
+		Description: `Returns a sorted JSON array of every category used by integrations. This is
+a good first call when exploring the structure of the package corpus.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listAllDistinctCategories)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_categories",
+		Description: `Returns a JSON array of {category, count} for every integration category,
+where count is the number of integrations in the category. Sorted by count,
+most used first.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listCategories)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_integrations",
+		Description: `Returns a JSON array with the name, title, version, and type of every
+integration package, sorted by name. No SQL knowledge is needed, so this is
+safe to call before fleetpkg_get_sql_tables.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listIntegrations)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_search_integrations",
+		Description: `Searches integrations for a keyword (case-insensitive substring match) in
+their name, title, or description. Returns up to 50 results with name, title, and
+description, sorted by name. Use this to find a package when the exact name is unknown.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.searchIntegrations)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_search_integrations_fts",
+		Description: `Full-text search over integration names, titles, and descriptions. query uses
+SQLite FTS5 syntax (e.g. "firewall logs", "aws OR gcp", or "kube*"). Returns up to 50
+results with name, title, and description ranked by BM25 relevance, best match first.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.searchIntegrationsFTS)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_integration",
+		Description: `Returns all metadata for the named integration as a single JSON object. In
+addition to the columns of the integrations table it contains "categories",
+"policy_templates", and "data_streams".`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getIntegration)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_readme",
+		Description: `Returns the markdown text of the named integration's docs/README.md. READMEs
+larger than 500 KB are truncated and end with a note saying so. The README text is
+also indexed for full-text search in the integration_readme_fts table.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getReadme)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_integration_summary",
+		Description: `Returns size statistics for the named integration as a single JSON object with
+integration_name, total_data_streams, total_fields, total_pipelines, total_processors,
+total_transforms, and total_changelog_entries. total_fields counts distinct field
+names per data stream and total_processors includes on_failure handlers.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getIntegrationSummary)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_owners",
+		Description: `Returns a JSON array of {github_login, owner_type, package_count} for every
+package owner (a GitHub team or user) and owner type (elastic, partner, community),
+sorted by package_count, largest first.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listOwners)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_build_dependencies",
+		Description: `Returns the ECS dependency declared in each integration's _dev/build/build.yml
+as {integration_name, ecs_reference, import_mappings}, sorted by ecs_reference.
+ecs_reference is the ECS git reference (e.g. git@v8.11.0) used to resolve "external: ecs"
+fields.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getBuildDependencies)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_ecs_versions",
+		Description: `Returns every ECS reference declared in _dev/build/build.yml files as
+{ecs_reference, count, integrations}, where integrations is the sorted list of
+integration names using the reference. Sorted by count, most used first. Useful for
+planning ECS upgrades.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listECSVersions)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_package_dependencies",
+		Description: `Returns the packages that integrations depend on as
+{integration_name, dependency_name, dependency_version_range}, sorted by integration.
+Dependencies are derived from the manifest conditions, so currently the only
+dependency is kibana with the conditions.kibana.version constraint. Set
+integration_name to only return the dependencies of one integration.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getPackageDependencies)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_compatible_integrations",
+		Description: `Returns integrations whose conditions.kibana.version constraint is satisfied by
+the given Kibana version, as {name, version, kibana_version_constraint}. Only the
+major.minor part of versions is compared, and only constraints built from ||
+alternatives of ^, ~, >=, and exact versions are understood. Integrations without
+a constraint are included.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getCompatibleIntegrations)
+}
+
+const distinctCategoriesQuery = `SELECT DISTINCT category FROM integration_categories ORDER BY category`
+
+func (t *tools) listAllDistinctCategories(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	rows, errResult := t.query(ctx, distinctCategoriesQuery)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	categories := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if c, ok := row["category"].(string); ok {
+			categories = append(categories, c)
+		}
+	}
+	return t.jsonResult(ctx, categories)
+}
+
+const listCategoriesQuery = `
+SELECT category, COUNT(*) AS count
+FROM integration_categories
+GROUP BY category
+ORDER BY count DESC, category`
+
+func (t *tools) listCategories(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, listCategoriesQuery)
+}
+
+const listIntegrationsQuery = `SELECT name, title, version, type FROM integrations ORDER BY name`
+
+type integrationSummary struct {
+	Name    string `json:"name"`
+	Title   string `json:"title"`
+	Version string `json:"version"`
+	Type    string `json:"type"`
+}
+
+func (t *tools) listIntegrations(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	rows, errResult := t.query(ctx, listIntegrationsQuery)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	integrations := make([]integrationSummary, 0, len(rows))
+	for _, row := range rows {
+		name, _ := row["name"].(string)
+		title, _ := row["title"].(string)
+		version, _ := row["version"].(string)
+		typ, _ := row["type"].(string)
+		integrations = append(integrations, integrationSummary{
+			Name:    name,
+			Title:   title,
+			Version: version,
+			Type:    typ,
+		})
+	}
+	return t.jsonResult(ctx, integrations)
+}
+
+type SearchIntegrationsArgs struct {
+	Keyword string `json:"keyword" jsonschema:"keyword to search for in the integration name, title, and description (e.g. nginx)"`
+}
+
+const searchIntegrationsQuery = `
+SELECT name, title, description
+FROM integrations
+WHERE name LIKE ?1 ESCAPE '\'
+   OR title LIKE ?1 ESCAPE '\'
+   OR description LIKE ?1 ESCAPE '\'
+ORDER BY name
+LIMIT 50`
+
+// likeEscaper escapes the LIKE wildcards of a term so that it is matched
+// literally when the pattern uses ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (t *tools) searchIntegrations(ctx context.Context, req *mcp.CallToolRequest, args SearchIntegrationsArgs) (*mcp.CallToolResult, any, error) {
+	if args.Keyword == "" {
+		return mcpErrorf("keyword is required"), nil, nil
+	}
+	return t.queryTool(ctx, searchIntegrationsQuery, "%"+likeEscaper.Replace(args.Keyword)+"%")
+}
+
+type SearchIntegrationsFTSArgs struct {
+	Query string `json:"query" jsonschema:"FTS5 full-text query matched against integration names, titles, and descriptions"`
+}
+
+const searchIntegrationsFTSQuery = `
+SELECT i.name, i.title, i.description
+FROM integrations_fts
+         JOIN integrations i ON integrations_fts.rowid = i.id
+WHERE integrations_fts MATCH ?
+ORDER BY bm25(integrations_fts)
+LIMIT 50`
+
+func (t *tools) searchIntegrationsFTS(ctx context.Context, req *mcp.CallToolRequest, args SearchIntegrationsFTSArgs) (*mcp.CallToolResult, any, error) {
+	if args.Query == "" {
+		return mcpErrorf("query is required"), nil, nil
+	}
+	return t.queryTool(ctx, searchIntegrationsFTSQuery, args.Query)
+}
+
+type GetIntegrationArgs struct {
+	Name string `json:"name" jsonschema:"name of the integration package (e.g. nginx)"`
+}
+
+const (
+	getIntegrationQuery = `SELECT * FROM integrations WHERE name = ? LIMIT 1`
+
+	integrationCategoriesQuery = `
+SELECT category
+FROM integration_categories
+WHERE integration_id = ?
+ORDER BY category`
+
+	integrationPolicyTemplatesQuery = `SELECT * FROM policy_templates WHERE integration_id = ? ORDER BY id`
+
+	integrationDataStreamsQuery = `SELECT * FROM data_streams WHERE integration_id = ? ORDER BY name`
+)
+
+func (t *tools) getIntegration(ctx context.Context, req *mcp.CallToolRequest, args GetIntegrationArgs) (*mcp.CallToolResult, any, error) {
+	if args.Name == "" {
+		return mcpErrorf("name is required"), nil, nil
+	}
+
+	rows, errResult := t.query(ctx, getIntegrationQuery, args.Name)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	if len(rows) == 0 {
+		return mcpErrorf("integration %q not found, use fleetpkg_search_integrations to find the package name", args.Name), nil, nil
+	}
+	rawJSONColumns(rows, "elasticsearch_privileges_cluster")
+	integration := rows[0]
+	id := integration["id"]
+
+	categoryRows, errResult := t.query(ctx, integrationCategoriesQuery, id)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	categories := make([]string, 0, len(categoryRows))
+	for _, row := range categoryRows {
+		if c, ok := row["category"].(string); ok {
+			categories = append(categories, c)
+		}
+	}
+
+	policyTemplates, errResult := t.query(ctx, integrationPolicyTemplatesQuery, id)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	dataStreams, errResult := t.query(ctx, integrationDataStreamsQuery, id)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	rawJSONColumns(dataStreams,
+		"elasticsearch_privileges_properties",
+		"elasticsearch_index_template_settings",
+		"elasticsearch_index_template_mappings")
+
+	integration["categories"] = categories
+	integration["policy_templates"] = policyTemplates
+	integration["data_streams"] = dataStreams
+	return t.jsonResult(ctx, integration)
+}
+
+type GetReadmeArgs struct {
+	Name string `json:"name" jsonschema:"name of the integration package (e.g. nginx)"`
+}
+
+const readmeQuery = `
+SELECT integration_readme.content, integration_readme.truncated
+FROM integration_readme
+         JOIN integrations ON integrations.id = integration_readme.integration_id
+WHERE integrations.name = ?
+LIMIT 1`
+
+func (t *tools) getReadme(ctx context.Context, req *mcp.CallToolRequest, args GetReadmeArgs) (*mcp.CallToolResult, any, error) {
+	if args.Name == "" {
+		return mcpErrorf("name is required"), nil, nil
+	}
+
+	rows, errResult := t.query(ctx, readmeQuery, args.Name)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	if len(rows) == 0 {
+		return mcpErrorf("no README found for integration %q", args.Name), nil, nil
+	}
+
+	text, _ := rows[0]["content"].(string)
+	if truncated, _ := rows[0]["truncated"].(int64); truncated != 0 {
+		text += "\n\n[README truncated at 500 KB]\n"
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+	}, nil, nil
+}
+
+type GetIntegrationSummaryArgs struct {
+	Name string `json:"name" jsonschema:"name of the integration package (e.g. nginx)"`
+}
+
+const integrationSummaryQuery = `
+SELECT integrations.name AS integration_name,
+       integration_stats.total_data_streams,
+       integration_stats.total_fields,
+       integration_stats.total_pipelines,
+       integration_stats.total_processors,
+       integration_stats.total_transforms,
+       integration_stats.total_changelog_entries
+FROM integration_stats
+         JOIN integrations ON integrations.id = integration_stats.integration_id
+WHERE integrations.name = ?
+LIMIT 1`
+
+func (t *tools) getIntegrationSummary(ctx context.Context, req *mcp.CallToolRequest, args GetIntegrationSummaryArgs) (*mcp.CallToolResult, any, error) {
+	if args.Name == "" {
+		return mcpErrorf("name is required"), nil, nil
+	}
+
+	rows, errResult := t.query(ctx, integrationSummaryQuery, args.Name)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	if len(rows) == 0 {
+		return mcpErrorf("integration %q not found, use fleetpkg_search_integrations to find the package name", args.Name), nil, nil
+	}
+	return t.jsonResult(ctx, rows[0])
+}
+
+const listOwnersQuery = `
+SELECT owner_github AS github_login, owner_type, COUNT(*) AS package_count
+FROM integrations
+GROUP BY owner_github, owner_type
+ORDER BY package_count DESC, owner_github`
+
+func (t *tools) listOwners(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, listOwnersQuery)
+}
+
+const buildDependenciesQuery = `
+SELECT integrations.name                                AS integration_name,
+       build_manifests.dependencies_ecs_reference       AS ecs_reference,
+       build_manifests.dependencies_ecs_import_mappings AS import_mappings
+FROM build_manifests
+         JOIN integrations ON integrations.id = build_manifests.integration_id
+ORDER BY build_manifests.dependencies_ecs_reference, integrations.name`
+
+func (t *tools) getBuildDependencies(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, buildDependenciesQuery)
+}
+
+type GetPackageDependenciesArgs struct {
+	IntegrationName string `json:"integration_name,omitempty" jsonschema:"optional name of an integration package to filter by (e.g. nginx)"`
+}
+
+const packageDependenciesQuery = `
+SELECT integrations.name                             AS integration_name,
+       package_dependencies.dependency_name,
+       package_dependencies.dependency_version_range
+FROM package_dependencies
+         JOIN integrations ON integrations.id = package_dependencies.integration_id
+WHERE ?1 = '' OR integrations.name = ?1
+ORDER BY integrations.name, package_dependencies.dependency_name`
+
+func (t *tools) getPackageDependencies(ctx context.Context, req *mcp.CallToolRequest, args GetPackageDependenciesArgs) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, packageDependenciesQuery, args.IntegrationName)
+}
+
+const listECSVersionsQuery = `
+SELECT ecs_version_refs.ecs_reference,
+       COUNT(*)                                                   AS count,
+       json_group_array(integrations.name ORDER BY integrations.name) AS integrations
+FROM ecs_version_refs
+         JOIN integrations ON integrations.id = ecs_version_refs.integration_id
+GROUP BY ecs_version_refs.ecs_reference
+ORDER BY count DESC, ecs_version_refs.ecs_reference`
+
+func (t *tools) listECSVersions(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	rows, errResult := t.query(ctx, listECSVersionsQuery)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	rawJSONColumns(rows, "integrations")
+	return t.jsonResult(ctx, rows)
+}
+
+type GetCompatibleIntegrationsArgs struct {
+	KibanaVersion string `json:"kibana_version" jsonschema:"Kibana version (e.g. 8.14.0)"`
+}
+
+const kibanaConstraintsQuery = `
+SELECT name, version, conditions_kibana_version AS kibana_version_constraint
+FROM integrations
+ORDER BY name`
+
+func (t *tools) getCompatibleIntegrations(ctx context.Context, req *mcp.CallToolRequest, args GetCompatibleIntegrationsArgs) (*mcp.CallToolResult, any, error) {
+	major, minor, ok := parseMajorMinor(args.KibanaVersion)
+	if !ok {
+		return mcpErrorf("kibana_version must be a version like 8.14.0"), nil, nil
+	}
+
+	rows, errResult := t.query(ctx, kibanaConstraintsQuery)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	compatible := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		constraint, _ := row["kibana_version_constraint"].(string)
+		if constraint == "" || kibanaConstraintSatisfied(constraint, major, minor) {
+			compatible = append(compatible, row)
+		}
+	}
+	return t.jsonResult(ctx, compatible)
+}
+
+// kibanaConstraintSatisfied reports whether a Kibana version with the given
+// major and minor satisfies the constraint. This is not a full semver range
+// implementation. The constraint is split on "||" and each alternative is
+// compared using only its major.minor:
+//
+//	^X.Y  same major and minor >= Y
+//	~X.Y  same major and minor
+//	>=X.Y major.minor >= X.Y
+//	X.Y   same major and minor
+//
+// Alternatives using any other syntax are never satisfied.
+func kibanaConstraintSatisfied(constraint string, major, minor int) bool {
+	for alt := range strings.SplitSeq(constraint, "||") {
+		alt = strings.TrimSpace(alt)
+
+		var op string
+		for _, prefix := range []string{">=", "^", "~", "="} {
+			if rest, ok := strings.CutPrefix(alt, prefix); ok {
+				op, alt = prefix, strings.TrimSpace(rest)
+				break
+			}
+		}
+
+		wantMajor, wantMinor, ok := parseMajorMinor(alt)
+		if !ok {
+			continue
+		}
+
+		switch op {
+		case "^":
+			if major == wantMajor && minor >= wantMinor {
+				return true
+			}
+		case ">=":
+			if major > wantMajor || (major == wantMajor && minor >= wantMinor) {
+				return true
+			}
+		default:
+			if major == wantMajor && minor == wantMinor {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseMajorMinor parses the major and minor numbers of a version such as
+// "8.14.0", "v8.14", or "8.14.0-SNAPSHOT".
+func parseMajorMinor(version string) (major, minor int, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(strings.TrimRightFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' }))
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
@@ -0,0 +1,355 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andrewkroh/fleetpkg-mcp/internal/database"
+)
+
+func TestListIntegrations(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+	)
+
+	res, _, err := tl.listIntegrations(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	var got []integrationSummary
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	assert.Equal(t, []integrationSummary{
+		{Name: "apache", Title: "apache", Version: "1.0.0", Type: "integration"},
+		{Name: "nginx", Title: "nginx", Version: "1.0.0", Type: "integration"},
+	}, got)
+}
+
+func TestListIntegrationsNotReady(t *testing.T) {
+	tl := newTools(nil, &atomic.Pointer[sql.DB]{}, slog.Default(), Options{})
+
+	res, _, err := tl.listIntegrations(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+	assert.Contains(t, resultText(t, res), "initializing")
+}
+
+func TestSearchIntegrations(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "apache"),
+		insertIntegrationSQL(2, "nginx"),
+		`UPDATE integrations SET description = 'Collects HTTP server access logs.' WHERE id = 2`,
+	)
+
+	res, _, err := tl.searchIntegrations(t.Context(), nil, SearchIntegrationsArgs{Keyword: "access log"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	var got []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, "nginx", got[0]["name"])
+	assert.Equal(t, "Collects HTTP server access logs.", got[0]["description"])
+
+	res, _, err = tl.searchIntegrations(t.Context(), nil, SearchIntegrationsArgs{})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
+
+func TestSearchIntegrationsWildcards(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "o365_metrics"),
+		insertIntegrationSQL(2, "o365a"),
+	)
+
+	search := func(keyword string) []string {
+		t.Helper()
+		res, _, err := tl.searchIntegrations(t.Context(), nil, SearchIntegrationsArgs{Keyword: keyword})
+		require.NoError(t, err)
+		require.False(t, res.IsError, resultText(t, res))
+
+		var got []map[string]any
+		require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+		names := []string{}
+		for _, row := range got {
+			names = append(names, row["name"].(string))
+		}
+		return names
+	}
+
+	// LIKE wildcards in the keyword are matched literally.
+	assert.Equal(t, []string{"o365_metrics"}, search("o365_"))
+	assert.Empty(t, search("%"))
+	assert.Empty(t, search(`o365\`))
+}
+
+func TestSearchIntegrationsFTS(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "apache"),
+		insertIntegrationSQL(2, "nginx"),
+		`UPDATE integrations SET description = 'Collects HTTP server access logs.' WHERE id = 2`,
+		`INSERT INTO integrations_fts (rowid, name, title, description) SELECT id, name, title, description FROM integrations`,
+	)
+
+	res, _, err := tl.searchIntegrationsFTS(t.Context(), nil, SearchIntegrationsFTSArgs{Query: "access logs"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	var got []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, "nginx", got[0]["name"])
+
+	res, _, err = tl.searchIntegrationsFTS(t.Context(), nil, SearchIntegrationsFTSArgs{})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
+
+func TestGetIntegration(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		`INSERT INTO integration_categories (integration_id, category) VALUES (1, 'web'), (1, 'observability')`,
+		`INSERT INTO policy_templates (id, integration_id, name, title, description) VALUES (1, 1, 'nginx', 'Nginx', 'Collect Nginx logs')`,
+		`INSERT INTO data_streams (id, integration_id, name, title, type, file_path)
+		 VALUES (1, 1, 'access', 'Access logs', 'logs', 'packages/nginx/data_stream/access')`,
+	)
+
+	t.Run("found", func(t *testing.T) {
+		res, _, err := tl.getIntegration(t.Context(), nil, GetIntegrationArgs{Name: "nginx"})
+		require.NoError(t, err)
+		require.False(t, res.IsError, resultText(t, res))
+
+		var got struct {
+			Name            string           `json:"name"`
+			Version         string           `json:"version"`
+			Categories      []string         `json:"categories"`
+			PolicyTemplates []map[string]any `json:"policy_templates"`
+			DataStreams     []map[string]any `json:"data_streams"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+		assert.Equal(t, "nginx", got.Name)
+		assert.Equal(t, "1.0.0", got.Version)
+		assert.Equal(t, []string{"observability", "web"}, got.Categories)
+		require.Len(t, got.PolicyTemplates, 1)
+		assert.Equal(t, "Nginx", got.PolicyTemplates[0]["title"])
+		require.Len(t, got.DataStreams, 1)
+		assert.Equal(t, "access", got.DataStreams[0]["name"])
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		res, _, err := tl.getIntegration(t.Context(), nil, GetIntegrationArgs{Name: "apache"})
+		require.NoError(t, err)
+		assert.True(t, res.IsError)
+		assert.Contains(t, resultText(t, res), `"apache" not found`)
+	})
+}
+
+func TestListCategories(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "apache"),
+		insertIntegrationSQL(2, "nginx"),
+		insertIntegrationSQL(3, "aws"),
+		`INSERT INTO integration_categories (integration_id, category) VALUES
+			(1, 'web'),
+			(2, 'web'),
+			(3, 'cloud')`,
+	)
+
+	res, _, err := tl.listCategories(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{"category":"web","count":2},{"category":"cloud","count":1}]`, resultText(t, res))
+}
+
+func TestListOwners(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "apache"),
+		insertIntegrationSQL(2, "nginx"),
+		insertIntegrationSQL(3, "acme"),
+		`UPDATE integrations SET owner_github = 'acme/integrations', owner_type = 'partner' WHERE id = 3`,
+	)
+
+	res, _, err := tl.listOwners(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"github_login":"elastic/integrations","owner_type":"elastic","package_count":2},
+		{"github_login":"acme/integrations","owner_type":"partner","package_count":1}
+	]`, resultText(t, res))
+}
+
+func TestGetBuildDependencies(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "apache"),
+		insertIntegrationSQL(2, "nginx"),
+		`INSERT INTO build_manifests (integration_id, dependencies_ecs_reference, dependencies_ecs_import_mappings, file_path) VALUES
+			(1, 'git@v8.17.0', 1, '_dev/build/build.yml'),
+			(2, 'git@v8.11.0', NULL, '_dev/build/build.yml')`,
+	)
+
+	res, _, err := tl.getBuildDependencies(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"integration_name":"nginx","ecs_reference":"git@v8.11.0","import_mappings":null},
+		{"integration_name":"apache","ecs_reference":"git@v8.17.0","import_mappings":1}
+	]`, resultText(t, res))
+}
+
+func TestListECSVersions(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+		insertIntegrationSQL(3, "aws"),
+		insertIntegrationSQL(4, "system"),
+		`INSERT INTO build_manifests (integration_id, dependencies_ecs_reference, file_path) VALUES
+			(1, 'git@v8.17.0', '_dev/build/build.yml'),
+			(2, 'git@v8.17.0', '_dev/build/build.yml'),
+			(3, 'git@v8.11.0', '_dev/build/build.yml'),
+			(4, NULL, '_dev/build/build.yml')`,
+	)
+	require.NoError(t, database.New(tl.db.Load()).PopulateEcsVersionRefs(t.Context()))
+
+	res, _, err := tl.listECSVersions(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"ecs_reference":"git@v8.17.0","count":2,"integrations":["apache","nginx"]},
+		{"ecs_reference":"git@v8.11.0","count":1,"integrations":["aws"]}
+	]`, resultText(t, res))
+}
+
+func TestGetPackageDependencies(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+		`INSERT INTO package_dependencies (integration_id, dependency_name, dependency_version_range) VALUES
+			(1, 'kibana', '^8.14.0 || ^9.0.0'),
+			(2, 'kibana', '^8.11.0')`,
+	)
+
+	res, _, err := tl.getPackageDependencies(t.Context(), nil, GetPackageDependenciesArgs{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"integration_name":"apache","dependency_name":"kibana","dependency_version_range":"^8.11.0"},
+		{"integration_name":"nginx","dependency_name":"kibana","dependency_version_range":"^8.14.0 || ^9.0.0"}
+	]`, resultText(t, res))
+
+	res, _, err = tl.getPackageDependencies(t.Context(), nil, GetPackageDependenciesArgs{IntegrationName: "nginx"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"integration_name":"nginx","dependency_name":"kibana","dependency_version_range":"^8.14.0 || ^9.0.0"}
+	]`, resultText(t, res))
+}
+
+func TestKibanaConstraintSatisfied(t *testing.T) {
+	tests := []struct {
+		constraint   string
+		major, minor int
+		want         bool
+	}{
+		{"^8.14.0", 8, 14, true},
+		{"^8.14.0", 8, 15, true},
+		{"^8.14.0", 8, 13, false},
+		{"^8.14.0", 9, 0, false},
+		{"^8.14.0 || ^9.0.0", 9, 1, true},
+		{"~8.14.0", 8, 14, true},
+		{"~8.14.0", 8, 15, false},
+		{">=8.10.0", 9, 0, true},
+		{">=8.10.0", 8, 9, false},
+		{"8.14.0", 8, 14, true},
+		{"<9.0.0", 8, 14, false},
+	}
+	for _, tc := range tests {
+		got := kibanaConstraintSatisfied(tc.constraint, tc.major, tc.minor)
+		assert.Equal(t, tc.want, got, "%s with %d.%d", tc.constraint, tc.major, tc.minor)
+	}
+}
+
+func TestGetCompatibleIntegrations(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "apache"),
+		insertIntegrationSQL(2, "nginx"),
+		insertIntegrationSQL(3, "aws"),
+		`UPDATE integrations SET conditions_kibana_version = '^8.13.0 || ^9.0.0' WHERE id = 1`,
+		`UPDATE integrations SET conditions_kibana_version = '^8.16.0' WHERE id = 2`,
+	)
+
+	res, _, err := tl.getCompatibleIntegrations(t.Context(), nil, GetCompatibleIntegrationsArgs{KibanaVersion: "8.14.0"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"name":"apache","version":"1.0.0","kibana_version_constraint":"^8.13.0 || ^9.0.0"},
+		{"name":"aws","version":"1.0.0","kibana_version_constraint":null}
+	]`, resultText(t, res))
+
+	res, _, err = tl.getCompatibleIntegrations(t.Context(), nil, GetCompatibleIntegrationsArgs{KibanaVersion: "latest"})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
+
+func TestGetReadme(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+		`INSERT INTO integration_readme (integration_id, content, truncated, file_path)
+		 VALUES (1, '# Nginx Integration', false, 'packages/nginx/docs/README.md'),
+		        (2, '# Apache', true, 'packages/apache/docs/README.md')`,
+	)
+
+	res, _, err := tl.getReadme(t.Context(), nil, GetReadmeArgs{Name: "nginx"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.Equal(t, "# Nginx Integration", resultText(t, res))
+
+	res, _, err = tl.getReadme(t.Context(), nil, GetReadmeArgs{Name: "apache"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.Contains(t, resultText(t, res), "README truncated")
+
+	res, _, err = tl.getReadme(t.Context(), nil, GetReadmeArgs{Name: "missing"})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+
+	res, _, err = tl.getReadme(t.Context(), nil, GetReadmeArgs{})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
+
+func TestGetIntegrationSummary(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		`INSERT INTO integration_stats (integration_id, total_data_streams, total_fields, total_pipelines,
+		                                total_processors, total_transforms, total_changelog_entries)
+		 VALUES (1, 3, 120, 4, 57, 0, 85)`,
+	)
+
+	res, _, err := tl.getIntegrationSummary(t.Context(), nil, GetIntegrationSummaryArgs{Name: "nginx"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `{
+		"integration_name": "nginx",
+		"total_data_streams": 3,
+		"total_fields": 120,
+		"total_pipelines": 4,
+		"total_processors": 57,
+		"total_transforms": 0,
+		"total_changelog_entries": 85
+	}`, resultText(t, res))
+
+	res, _, err = tl.getIntegrationSummary(t.Context(), nil, GetIntegrationSummaryArgs{Name: "missing"})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
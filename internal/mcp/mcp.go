@@ -7,35 +7,97 @@ package mcp
 import (
 	"context"
 	"database/sql"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"regexp"
+	"slices"
 	"strings"
 	"sync/atomic"
+	"time"
+	"unicode"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/andrewkroh/fleetpkg-mcp/internal/database"
+	"github.com/andrewkroh/fleetpkg-mcp/internal/metrics"
 )
 
+// Options configures the behavior of the tools.
+type Options struct {
+	// MaxRows is the maximum number of rows that fleetpkg_execute_sql_query
+	// will read from a result set. Zero means no limit.
+	MaxRows int
+
+	// SlowQueryThreshold, when greater than zero, causes queries that take
+	// longer than this to be logged at WARN level.
+	SlowQueryThreshold time.Duration
+
+	// AuditLog, when set, receives a record of every query executed by
+	// fleetpkg_execute_sql_query.
+	AuditLog *AuditLog
+
+	// Tracer, when set, is used to record a span for each
+	// fleetpkg_execute_sql_query invocation.
+	Tracer trace.Tracer
+
+	// MaxQueryTime, when greater than zero, is the maximum time that a
+	// fleetpkg_execute_sql_query statement may run before it is cancelled.
+	MaxQueryTime time.Duration
+
+	// MaxResultBytes, when greater than zero, is the maximum size of the
+	// JSON encoded rows returned by fleetpkg_execute_sql_query. Rows beyond
+	// the limit are dropped and the result is marked as truncated.
+	MaxResultBytes int
+
+	// DBPath is the location of the SQLite database file reported by
+	// fleetpkg_get_db_stats. It is empty for an in-memory database.
+	DBPath string
+
+	// Reload, when set, rebuilds the database and swaps it in place of the
+	// current one. fleetpkg_reload calls it in the background and is only
+	// registered when Reload is set.
+	Reload func()
+}
+
 type tools struct {
-	tables []string
-	db     *atomic.Pointer[sql.DB]
-	log    *slog.Logger
+	tables    []string
+	db        *atomic.Pointer[sql.DB]
+	log       *slog.Logger
+	opts      Options
+	start     time.Time   // Time at which the tools were created.
+	reloading atomic.Bool // Set while a reload started by fleetpkg_reload runs.
 }
 
-func newTools(tables []string, db *atomic.Pointer[sql.DB], log *slog.Logger) *tools {
+func newTools(tables []string, db *atomic.Pointer[sql.DB], log *slog.Logger, opts Options) *tools {
 	return &tools{
 		tables: tables,
 		db:     db,
 		log:    log,
+		opts:   opts,
+		start:  time.Now(),
 	}
 }
 
-func AddTools(s *mcp.Server, tables []string, db *atomic.Pointer[sql.DB], log *slog.Logger) {
-	t := newTools(tables, db, log)
+// AddCapabilities registers the fleetpkg tools, resources, and prompts with
+// the server.
+func AddCapabilities(s *mcp.Server, tables []string, db *atomic.Pointer[sql.DB], log *slog.Logger, opts Options) {
+	t := newTools(tables, db, log, opts)
+
+	addResources(s, t)
+	addPrompts(s)
 
 	mcp.AddTool(s, &mcp.Tool{
-		Name:        "fleetpkg_get_sql_tables",
-		Description: `Call this tool first! Returns the complete catalog of available tables and columns.`,
+		Name: "fleetpkg_get_sql_tables",
+		Description: `Call this tool first! Returns the complete catalog of available tables and columns.
+Set format to "markdown" to get a table of column names, types, and nullability per table
+instead of the SQL DDL.`,
 		Annotations: &mcp.ToolAnnotations{
 			IdempotentHint: true,
 			ReadOnlyHint:   true,
@@ -45,16 +107,118 @@ func AddTools(s *mcp.Server, tables []string, db *atomic.Pointer[sql.DB], log *s
 	mcp.AddTool(s, &mcp.Tool{
 		Name: "fleetpkg_execute_sql_query",
 		Description: `Call this tool to execute an arbitrary SQLite query.
-Be sure you have called fleetpkg_get_sql_tables() first to understand the structure of the data!`,
+Be sure you have called fleetpkg_get_sql_tables() first to understand the structure of the data!
+The response is {"schema": [{"name": "...", "type": "..."}, ...], "rows": [...]} where
+type is the declared SQLite column type (empty for expressions).
+Set limit (and optionally offset) to page through large result sets; the response then
+also contains "offset", "limit", and "has_more". A statement with its own LIMIT clause,
+or an EXPLAIN statement, is run as written and the paging fields are omitted. When the
+rows exceed the result size limit the remaining rows are dropped and the response
+contains "is_truncated": true.
+Pass values with params instead of writing them into the statement. Each ? placeholder
+is bound to the next value of params, and ?NNN binds the NNN-th value (1-based), e.g.
+{"statement": "SELECT * FROM integrations WHERE name = ?", "params": ["nginx"]}.
+Bound values are always treated as literals, never as SQL.
+Set output_format to "ndjson" to receive one JSON object per row and line, or to "csv"
+to receive a header row followed by the rows. These formats contain only the rows.`,
 		Annotations: &mcp.ToolAnnotations{
 			IdempotentHint: true,
 			ReadOnlyHint:   true,
 		},
 	}, t.executeQuery)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_explain_query",
+		Description: `Runs EXPLAIN QUERY PLAN for a SQLite query and returns the plan as a text
+table (id | parent | notused | detail). Use this to check whether a query uses
+indexes or performs full table scans before running it.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.explainQuery)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_run_query_plan",
+		Description: `Runs EXPLAIN QUERY PLAN for a SQLite query and returns the plan as an ASCII tree,
+nested according to each step's parent. "SCAN <table>" steps indicate full table
+scans that could benefit from an index; "SEARCH <table> USING INDEX" steps use one.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.runQueryPlan)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_is_ready",
+		Description: `Reports whether the database has finished initializing. Returns
+{"ready": bool, "elapsed_ms": N} where elapsed_ms is the time since the server
+started. Poll this tool if other tools report that the database is initializing.`,
+		Annotations: &mcp.ToolAnnotations{
+			ReadOnlyHint: true,
+		},
+	}, t.isReady)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_table_row_counts",
+		Description: `Returns a JSON object mapping each table name to its number of rows. Useful
+for orientation before writing queries.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getTableRowCounts)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_db_stats",
+		Description: `Returns {"db_path": "...", "file_size_bytes": N, "tables": [{"name": "...", "rows": N}]}
+describing the SQLite database file and the number of rows in every table.
+db_path is empty and file_size_bytes is 0 for an in-memory database.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getDBStats)
+
+	if opts.Reload != nil {
+		mcp.AddTool(s, &mcp.Tool{
+			Name: "fleetpkg_reload",
+			Description: `Rebuilds the database from the integration packages on disk, e.g. after
+pulling new changes. Returns {"status": "reload_started"} immediately while the reload
+runs in the background, or {"status": "reload_in_progress"} if a reload is already
+running. Queries are served from the current database until the reload completes.`,
+			Annotations: &mcp.ToolAnnotations{
+				IdempotentHint: true,
+			},
+		}, t.reload)
+	}
+
+	addIntegrationTools(s, t)
+	addDataStreamTools(s, t)
+	addFieldTools(s, t)
+	addChangelogTools(s, t)
+	addIngestPipelineTools(s, t)
+	addPolicyTemplateTools(s, t)
+	addVarTools(s, t)
+	addIconTools(s, t)
+	addTransformTools(s, t)
+}
+
+type GetSQLTablesArgs struct {
+	Format string `json:"format,omitempty" jsonschema:"output format, either sql (default) for the CREATE TABLE statements or markdown for a table of columns per table"`
 }
 
-func (t *tools) getSQLTables(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
-	schemas := strings.Join(t.tables, "\n")
+func (t *tools) getSQLTables(ctx context.Context, req *mcp.CallToolRequest, args GetSQLTablesArgs) (*mcp.CallToolResult, any, error) {
+	var schemas string
+	switch args.Format {
+	case "", "sql":
+		schemas = strings.Join(t.tables, "\n")
+	case "markdown":
+		schemas = tablesMarkdown(t.tables)
+	default:
+		return mcpErrorf("invalid format %q (must be sql or markdown)", args.Format), nil, nil
+	}
+
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
 			&mcp.TextContent{Text: schemas},
@@ -62,71 +226,826 @@ func (t *tools) getSQLTables(ctx context.Context, req *mcp.CallToolRequest, _ st
 	}, nil, nil
 }
 
+var (
+	createTableRegex = regexp.MustCompile(`CREATE (?:VIRTUAL )?TABLE(?: IF NOT EXISTS)? (\w+)`)
+	columnDefRegex   = regexp.MustCompile(`^\s*(\w+)(?:\s+([A-Z]+)\b)?([^-]*)`)
+)
+
+// tablesMarkdown renders CREATE TABLE statements as Markdown with one
+// "| column | type | nullable |" table per SQL table. The leading comment of
+// each statement is kept as the table description. It relies on the DDL
+// having one column definition per line, as the generated schema does.
+func tablesMarkdown(tables []string) string {
+	var sb strings.Builder
+	for _, ddl := range tables {
+		m := createTableRegex.FindStringSubmatch(ddl)
+		if m == nil {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "## %s\n\n", m[1])
+
+		var header bool
+		for line := range strings.Lines(ddl) {
+			line = strings.TrimRight(line, "\n")
+			if !header {
+				if desc, ok := strings.CutPrefix(line, "-- "); ok {
+					fmt.Fprintf(&sb, "%s\n\n", desc)
+					continue
+				}
+				sb.WriteString("| column | type | nullable |\n| --- | --- | --- |\n")
+				header = true
+				continue
+			}
+
+			c := columnDefRegex.FindStringSubmatch(line)
+			if c == nil {
+				continue
+			}
+			switch c[1] {
+			case "PRIMARY", "FOREIGN", "UNIQUE", "CHECK", "CONSTRAINT":
+				continue
+			}
+			// Columns of FTS5 virtual tables are untyped and hold text.
+			typ := c[2]
+			if typ == "" {
+				typ = "TEXT"
+			}
+			nullable := "yes"
+			if strings.Contains(c[3], "NOT NULL") || strings.Contains(c[3], "PRIMARY KEY") {
+				nullable = "no"
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %s |\n", c[1], typ, nullable)
+		}
+	}
+	return sb.String()
+}
+
+func (t *tools) getTableRowCounts(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	db := t.db.Load()
+	if db == nil {
+		t.log.WarnContext(ctx, "Database not ready yet")
+		return mcpErrorf("database is still initializing, please retry in a moment"), nil, nil
+	}
+
+	counts := make(map[string]int64, len(database.TableNames))
+	for _, table := range database.TableNames {
+		var count int64
+		// Table names come from the generated schema and are safe to format.
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+			t.log.ErrorContext(ctx, "Error counting rows", slog.String("table", table), slog.Any("error", err))
+			return mcpErrorf("failed to count rows in %s: %v", table, err), nil, nil
+		}
+		counts[table] = count
+	}
+
+	return t.jsonResult(ctx, counts)
+}
+
+// dbStats is the response of fleetpkg_get_db_stats.
+type dbStats struct {
+	DBPath        string       `json:"db_path"`
+	FileSizeBytes int64        `json:"file_size_bytes"`
+	Tables        []tableStats `json:"tables"`
+}
+
+type tableStats struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+func (t *tools) getDBStats(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	db := t.db.Load()
+	if db == nil {
+		t.log.WarnContext(ctx, "Database not ready yet")
+		return mcpErrorf("database is still initializing, please retry in a moment"), nil, nil
+	}
+
+	stats := dbStats{
+		DBPath: t.opts.DBPath,
+		Tables: []tableStats{},
+	}
+	if stats.DBPath != "" {
+		info, err := os.Stat(stats.DBPath)
+		if err != nil {
+			t.log.ErrorContext(ctx, "Error reading database file size", slog.Any("error", err))
+			return mcpErrorf("failed to stat database file: %v", err), nil, nil
+		}
+		stats.FileSizeBytes = info.Size()
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT name FROM sqlite_master
+WHERE type = 'table'
+  AND name NOT LIKE 'sqlite_%'
+  AND name NOT IN (SELECT name FROM pragma_table_list WHERE type = 'shadow')
+ORDER BY name`)
+	if err != nil {
+		t.log.ErrorContext(ctx, "Error listing tables", slog.Any("error", err))
+		return mcpErrorf("failed to list tables: %v", err), nil, nil
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			rows.Close()
+			return mcpErrorf("failed to list tables: %v", err), nil, nil
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return mcpErrorf("failed to list tables: %v", err), nil, nil
+	}
+
+	for _, name := range names {
+		var count int64
+		quoted := `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+		if err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quoted).Scan(&count); err != nil {
+			t.log.ErrorContext(ctx, "Error counting rows", slog.String("table", name), slog.Any("error", err))
+			return mcpErrorf("failed to count rows in %s: %v", name, err), nil, nil
+		}
+		stats.Tables = append(stats.Tables, tableStats{Name: name, Rows: count})
+	}
+
+	return t.jsonResult(ctx, stats)
+}
+
+type isReadyResult struct {
+	Ready     bool  `json:"ready"`
+	ElapsedMS int64 `json:"elapsed_ms"`
+}
+
+func (t *tools) isReady(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	return t.jsonResult(ctx, isReadyResult{
+		Ready:     t.db.Load() != nil,
+		ElapsedMS: time.Since(t.start).Milliseconds(),
+	})
+}
+
+type reloadResult struct {
+	Status string `json:"status"` // Either reload_started or reload_in_progress.
+}
+
+func (t *tools) reload(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	if t.opts.Reload == nil {
+		return mcpErrorf("reload is not supported"), nil, nil
+	}
+	if !t.reloading.CompareAndSwap(false, true) {
+		return t.jsonResult(ctx, reloadResult{Status: "reload_in_progress"})
+	}
+
+	go func() {
+		defer t.reloading.Store(false)
+		t.opts.Reload()
+	}()
+	return t.jsonResult(ctx, reloadResult{Status: "reload_started"})
+}
+
 type ExecuteQueryArgs struct {
-	Statement string `json:"statement" jsonschema:"SQLite query to execute"`
+	Statement    string `json:"statement" jsonschema:"SQLite query to execute"`
+	Offset       int64  `json:"offset,omitempty" jsonschema:"number of rows to skip when limit is set"`
+	Limit        int64  `json:"limit,omitempty" jsonschema:"maximum number of rows to return in this page (0 means no paging)"`
+	Params       []any  `json:"params,omitempty" jsonschema:"values bound to the ? placeholders of the statement, in order"`
+	OutputFormat string `json:"output_format,omitempty" jsonschema:"format of the result: json (default), ndjson, or csv"`
 }
 
-func (t *tools) executeQuery(ctx context.Context, req *mcp.CallToolRequest, args ExecuteQueryArgs) (*mcp.CallToolResult, any, error) {
+// queryResult is the response of fleetpkg_execute_sql_query.
+type queryResult struct {
+	Schema      []columnSchema   `json:"schema"`
+	Rows        []map[string]any `json:"rows"`
+	IsTruncated bool             `json:"is_truncated,omitempty"` // Rows were dropped to stay within MaxResultBytes.
+	*pageInfo
+}
+
+// columnSchema describes a result column.
+type columnSchema struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // Declared type, empty for expressions.
+}
+
+// pageInfo is included in a queryResult when paging is requested.
+type pageInfo struct {
+	Offset  int64 `json:"offset"`
+	Limit   int64 `json:"limit"`
+	HasMore bool  `json:"has_more"`
+}
+
+// paginate appends a parameterized LIMIT and OFFSET to the statement. It
+// returns the statement and the arguments to bind. EXPLAIN statements and
+// statements that have their own top-level LIMIT clause are returned
+// unchanged with no arguments.
+func paginate(statement string, limit, offset int64) (string, []any) {
+	stmt := strings.TrimRight(strings.TrimSpace(stripComments(statement)), "; \t\n")
+	words := sqlWords(stmt)
+	if len(words) > 0 && strings.EqualFold(words[0].text, "EXPLAIN") {
+		return statement, nil
+	}
+	for _, w := range words {
+		if w.depth == 0 && strings.EqualFold(w.text, "LIMIT") {
+			return statement, nil
+		}
+	}
+	return stmt + "\nLIMIT ? OFFSET ?", []any{limit, offset}
+}
+
+func (t *tools) executeQuery(ctx context.Context, req *mcp.CallToolRequest, args ExecuteQueryArgs) (res *mcp.CallToolResult, _ any, err error) {
+	span := trace.SpanFromContext(ctx)
+	if t.opts.Tracer != nil {
+		ctx, span = t.opts.Tracer.Start(ctx, "executeQuery",
+			trace.WithAttributes(attribute.String("db.statement", args.Statement)))
+		defer span.End()
+	}
+
+	start := time.Now()
+	var rowCount int
+	defer func() {
+		elapsed := time.Since(start)
+		failed := err != nil || res == nil || res.IsError
+		metrics.ObserveQuery(elapsed, failed)
+		if failed {
+			span.SetStatus(codes.Error, "query failed")
+		}
+		if t.opts.AuditLog != nil {
+			t.audit(ctx, args.Statement, rowCount, elapsed, res, err)
+		}
+	}()
+
 	db := t.db.Load()
 	if db == nil {
 		t.log.WarnContext(ctx, "Database not ready yet")
 		return mcpErrorf("database is still initializing, please retry in a moment"), nil, nil
 	}
 
+	if errResult := t.validateStatement(ctx, args.Statement); errResult != nil {
+		return errResult, nil, nil
+	}
+
+	switch args.OutputFormat {
+	case "", "json", "ndjson", "csv":
+	default:
+		return mcpErrorf("invalid output_format %q: must be json, ndjson, or csv", args.OutputFormat), nil, nil
+	}
+
 	t.log.InfoContext(ctx, "Executing query", slog.String("statement", args.Statement))
 
-	rows, err := db.QueryContext(ctx, args.Statement)
+	statement := args.Statement
+	queryArgs := slices.Clone(args.Params)
+	var paged bool
+	if args.Limit > 0 {
+		var pageArgs []any
+		statement, pageArgs = paginate(statement, args.Limit, args.Offset)
+		queryArgs = append(queryArgs, pageArgs...)
+		paged = pageArgs != nil
+	}
+
+	queryCtx := ctx
+	if t.opts.MaxQueryTime > 0 {
+		var cancel context.CancelFunc
+		queryCtx, cancel = context.WithTimeout(ctx, t.opts.MaxQueryTime)
+		defer cancel()
+	}
+
+	rows, err := db.QueryContext(queryCtx, statement, queryArgs...)
 	if err != nil {
+		if errors.Is(queryCtx.Err(), context.DeadlineExceeded) {
+			return t.queryTimeoutError(ctx, args.Statement), nil, nil
+		}
 		t.log.ErrorContext(ctx, "error executing query", slog.Any("error", err))
 		return mcpErrorf("failed to execute query: %v", err), nil, nil
 	}
 	defer rows.Close()
 
-	columns, err := rows.Columns()
+	columnTypes, err := rows.ColumnTypes()
 	if err != nil {
 		t.log.ErrorContext(ctx, "Error getting columns", slog.Any("error", err))
 		return mcpErrorf("failed to get columns: %v", err), nil, nil
 	}
+	columns := make([]string, len(columnTypes))
+	schema := make([]columnSchema, len(columnTypes))
+	for i, ct := range columnTypes {
+		columns[i] = ct.Name()
+		schema[i] = columnSchema{Name: ct.Name(), Type: ct.DatabaseTypeName()}
+	}
 
-	var result []map[string]interface{}
+	result := []map[string]any{}
+	var resultBytes int
+	var truncated bool
 	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		pointers := make([]interface{}, len(columns))
-		for i := range values {
-			pointers[i] = &values[i]
+		// Stop before materializing rows beyond the limit.
+		if t.opts.MaxRows > 0 && len(result) >= t.opts.MaxRows {
+			t.log.WarnContext(ctx, "Query exceeded row limit", slog.Int("max_rows", t.opts.MaxRows))
+			return rowLimitError(len(result), t.opts.MaxRows), nil, nil
 		}
 
-		if err := rows.Scan(pointers...); err != nil {
+		row, err := scanRow(rows, columns)
+		if err != nil {
 			t.log.ErrorContext(ctx, "Error scanning row", slog.Any("error", err))
 			return mcpErrorf("failed to scan row: %v", err), nil, nil
 		}
 
-		row := make(map[string]interface{})
-		for i, column := range columns {
-			val := values[i]
-			if b, ok := val.([]byte); ok {
-				row[column] = string(b)
-			} else {
-				row[column] = val
+		if t.opts.MaxResultBytes > 0 {
+			data, err := json.Marshal(row)
+			if err != nil {
+				t.log.ErrorContext(ctx, "Error marshaling row", slog.Any("error", err))
+				return mcpErrorf("failed to marshal row: %v", err), nil, nil
+			}
+			if resultBytes += len(data); resultBytes > t.opts.MaxResultBytes {
+				t.log.WarnContext(ctx, "Query result truncated",
+					slog.Int("max_result_bytes", t.opts.MaxResultBytes),
+					slog.Int("row_count", len(result)))
+				truncated = true
+				break
 			}
 		}
 		result = append(result, row)
 	}
+	if err = rows.Err(); err != nil {
+		if errors.Is(queryCtx.Err(), context.DeadlineExceeded) {
+			return t.queryTimeoutError(ctx, args.Statement), nil, nil
+		}
+		t.log.ErrorContext(ctx, "Error iterating rows", slog.Any("error", err))
+		return mcpErrorf("failed to read rows: %v", err), nil, nil
+	}
+
+	rowCount = len(result)
+	t.log.InfoContext(ctx, "Query executed successfully", slog.Int("row_count", len(result)))
+	if elapsed := time.Since(start); t.opts.SlowQueryThreshold > 0 && elapsed > t.opts.SlowQueryThreshold {
+		t.log.WarnContext(ctx, "Slow query",
+			slog.String("statement", args.Statement),
+			slog.Duration("duration", elapsed),
+			slog.Int("row_count", len(result)))
+	}
+	span.SetAttributes(attribute.Int("db.row_count", len(result)))
 
-	jsonRows, err := json.Marshal(result)
+	var text string
+	switch args.OutputFormat {
+	case "ndjson":
+		text, err = ndjsonText(result)
+	case "csv":
+		text, err = csvText(columns, result)
+	default:
+		out := queryResult{Schema: schema, Rows: result, IsTruncated: truncated}
+		if paged {
+			out.pageInfo = &pageInfo{
+				Offset:  args.Offset,
+				Limit:   args.Limit,
+				HasMore: truncated || int64(len(result)) == args.Limit,
+			}
+		}
+		return t.jsonResult(ctx, out)
+	}
+	if err != nil {
+		t.log.ErrorContext(ctx, "Error formatting results", slog.Any("error", err))
+		return mcpErrorf("failed to format result: %v", err), nil, nil
+	}
+	res = &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+	}
+	// The text formats have no field for the flag so a note follows the rows.
+	if truncated {
+		res.Content = append(res.Content, &mcp.TextContent{
+			Text: fmt.Sprintf("[result truncated after %d rows at %d bytes]", len(result), t.opts.MaxResultBytes),
+		})
+	}
+	return res, nil, nil
+}
+
+// queryTimeoutError logs and returns the error for a statement that was
+// cancelled after running for longer than MaxQueryTime.
+func (t *tools) queryTimeoutError(ctx context.Context, statement string) *mcp.CallToolResult {
+	t.log.WarnContext(ctx, "Query timed out",
+		slog.String("statement", statement),
+		slog.Duration("max_query_time", t.opts.MaxQueryTime))
+	return mcpErrorf("query timed out after %v", t.opts.MaxQueryTime)
+}
+
+func (t *tools) explainQuery(ctx context.Context, req *mcp.CallToolRequest, args ExecuteQueryArgs) (*mcp.CallToolResult, any, error) {
+	if errResult := t.validateStatement(ctx, args.Statement); errResult != nil {
+		return errResult, nil, nil
+	}
+
+	rows, errResult := t.query(ctx, "EXPLAIN QUERY PLAN "+args.Statement, args.Params...)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("id | parent | notused | detail\n")
+	for _, row := range rows {
+		fmt.Fprintf(&sb, "%v | %v | %v | %v\n", row["id"], row["parent"], row["notused"], row["detail"])
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: sb.String()},
+		},
+	}, nil, nil
+}
+
+type RunQueryPlanArgs struct {
+	Statement string `json:"statement" jsonschema:"SQLite query to explain"`
+}
+
+func (t *tools) runQueryPlan(ctx context.Context, req *mcp.CallToolRequest, args RunQueryPlanArgs) (*mcp.CallToolResult, any, error) {
+	if errResult := t.validateStatement(ctx, args.Statement); errResult != nil {
+		return errResult, nil, nil
+	}
+
+	rows, errResult := t.query(ctx, "EXPLAIN QUERY PLAN "+args.Statement)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: queryPlanTree(rows)},
+		},
+	}, nil, nil
+}
+
+// queryPlanTree renders EXPLAIN QUERY PLAN rows as a tree in the same style
+// as the sqlite3 shell. Rows are nested beneath the row whose id matches
+// their parent.
+func queryPlanTree(rows []map[string]any) string {
+	children := map[int64][]map[string]any{}
+	for _, row := range rows {
+		parent, _ := row["parent"].(int64)
+		children[parent] = append(children[parent], row)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("QUERY PLAN\n")
+
+	var walk func(parent int64, prefix string)
+	walk = func(parent int64, prefix string) {
+		for i, row := range children[parent] {
+			branch, indent := "|--", "|  "
+			if i == len(children[parent])-1 {
+				branch, indent = "`--", "   "
+			}
+			fmt.Fprintf(&sb, "%s%s%v\n", prefix, branch, row["detail"])
+
+			// A row is never its own parent, but guard against a cycle.
+			if id, _ := row["id"].(int64); id != parent {
+				walk(id, prefix+indent)
+			}
+		}
+	}
+	walk(0, "")
+
+	return sb.String()
+}
+
+// validateStatement checks a user supplied statement before it is passed to
+// the driver. The statement must be read-only and contain a single statement
+// because the driver executes every statement of a query. If the statement
+// is rejected then a non-nil tool result describing the error is returned.
+func (t *tools) validateStatement(ctx context.Context, statement string) *mcp.CallToolResult {
+	if err := checkReadOnly(statement); err != nil {
+		t.log.WarnContext(ctx, "Rejected query", slog.String("statement", statement), slog.Any("error", err))
+		return mcpErrorf("%v", err)
+	}
+	if n := countStatements(statement); n > 1 {
+		t.log.WarnContext(ctx, "Rejected query", slog.String("statement", statement), slog.Int("statements", n))
+		return mcpErrorf("only one statement per call is allowed; received %d statements", n)
+	}
+	return nil
+}
+
+// readOnlyKeywords are the leading keywords permitted in a statement.
+var readOnlyKeywords = []string{"SELECT", "WITH", "EXPLAIN"}
+
+// writeKeywords are the keywords of statements that modify data. They are
+// rejected anywhere in a statement because a WITH clause may be followed by
+// any of them.
+var writeKeywords = []string{"INSERT", "UPDATE", "DELETE", "REPLACE"}
+
+// checkReadOnly returns an error if the statement does not begin with one of
+// the readOnlyKeywords after comments are removed, or if it contains one of
+// the writeKeywords outside of a quoted string or identifier. This is a guard
+// against statements like ATTACH or PRAGMA that can alter runtime state even
+// though the database is opened read-only.
+func checkReadOnly(statement string) error {
+	stmt := strings.TrimSpace(stripComments(statement))
+	if stmt == "" {
+		return errors.New("statement is empty")
+	}
+
+	end := strings.IndexFunc(stmt, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	keyword := stmt
+	if end >= 0 {
+		keyword = stmt[:end]
+	}
+
+	if !slices.ContainsFunc(readOnlyKeywords, func(k string) bool { return strings.EqualFold(keyword, k) }) {
+		return fmt.Errorf("only read-only statements beginning with %s are allowed", strings.Join(readOnlyKeywords, ", "))
+	}
+
+	for _, w := range sqlWords(stmt) {
+		for _, k := range writeKeywords {
+			// REPLACE is also the name of a string function.
+			if strings.EqualFold(w.text, k) && !(k == "REPLACE" && w.call) {
+				return fmt.Errorf("%s statements are not allowed", strings.ToUpper(w.text))
+			}
+		}
+	}
+	return nil
+}
+
+// sqlWord is an unquoted identifier or keyword of a SQL statement.
+type sqlWord struct {
+	text  string
+	depth int  // Number of enclosing parentheses.
+	call  bool // Followed by '(' as in a function call.
+}
+
+// sqlWords returns the unquoted identifiers and keywords of a statement from
+// which comments have been removed. Quoted strings and identifiers are
+// skipped.
+func sqlWords(stmt string) []sqlWord {
+	var words []sqlWord
+	var depth int
+	for i := 0; i < len(stmt); i++ {
+		c := stmt[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			// Skip the quoted section. A doubled quote is an escaped quote
+			// and is handled by re-entering this case.
+			if c == '[' {
+				c = ']'
+			}
+			end := strings.IndexByte(stmt[i+1:], c)
+			if end < 0 {
+				return words
+			}
+			i += end + 1
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case isIdentByte(c):
+			start := i
+			for i+1 < len(stmt) && isIdentByte(stmt[i+1]) {
+				i++
+			}
+			rest := strings.TrimLeftFunc(stmt[i+1:], unicode.IsSpace)
+			words = append(words, sqlWord{
+				text:  stmt[start : i+1],
+				depth: depth,
+				call:  strings.HasPrefix(rest, "("),
+			})
+		}
+	}
+	return words
+}
+
+// isIdentByte reports whether c may be part of an unquoted SQL identifier
+// or keyword.
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// stripComments removes '--' line comments and '/* */' block comments from a
+// SQL statement. Comment markers that appear inside quoted strings or
+// identifiers are preserved.
+func stripComments(statement string) string {
+	var sb strings.Builder
+	for i := 0; i < len(statement); i++ {
+		c := statement[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			// Copy the quoted section verbatim. A doubled quote is an
+			// escaped quote and is handled by re-entering this case.
+			end := strings.IndexByte(statement[i+1:], c)
+			if end < 0 {
+				sb.WriteString(statement[i:])
+				return sb.String()
+			}
+			sb.WriteString(statement[i : i+end+2])
+			i += end + 1
+		case c == '-' && i+1 < len(statement) && statement[i+1] == '-':
+			end := strings.IndexByte(statement[i:], '\n')
+			if end < 0 {
+				return sb.String()
+			}
+			sb.WriteByte(' ')
+			i += end
+		case c == '/' && i+1 < len(statement) && statement[i+1] == '*':
+			end := strings.Index(statement[i+2:], "*/")
+			if end < 0 {
+				return sb.String()
+			}
+			sb.WriteByte(' ')
+			i += end + 3
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// countStatements returns the number of ';' separated statements in a SQL
+// string. Semicolons inside comments, quoted strings, and quoted identifiers
+// are ignored, as are empty statements such as the one after a trailing ';'.
+func countStatements(statement string) int {
+	stmt := stripComments(statement)
+
+	var count int
+	var nonEmpty bool
+	for i := 0; i < len(stmt); i++ {
+		switch c := stmt[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			// Skip the quoted section. A doubled quote is an escaped quote
+			// and is handled by re-entering this case.
+			nonEmpty = true
+			end := strings.IndexByte(stmt[i+1:], c)
+			if end < 0 {
+				i = len(stmt)
+				continue
+			}
+			i += end + 1
+		case c == ';':
+			if nonEmpty {
+				count++
+			}
+			nonEmpty = false
+		case !unicode.IsSpace(rune(c)):
+			nonEmpty = true
+		}
+	}
+	if nonEmpty {
+		count++
+	}
+	return count
+}
+
+// query executes a fixed, read-only query on behalf of a tool. If the
+// database is not ready or the query fails then a non-nil tool result
+// describing the error is returned.
+func (t *tools) query(ctx context.Context, statement string, args ...any) ([]map[string]any, *mcp.CallToolResult) {
+	db := t.db.Load()
+	if db == nil {
+		t.log.WarnContext(ctx, "Database not ready yet")
+		return nil, mcpErrorf("database is still initializing, please retry in a moment")
+	}
+
+	rows, err := db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		t.log.ErrorContext(ctx, "error executing query", slog.Any("error", err))
+		return nil, mcpErrorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		t.log.ErrorContext(ctx, "Error getting columns", slog.Any("error", err))
+		return nil, mcpErrorf("failed to get columns: %v", err)
+	}
+
+	result := []map[string]any{}
+	for rows.Next() {
+		row, err := scanRow(rows, columns)
+		if err != nil {
+			t.log.ErrorContext(ctx, "Error scanning row", slog.Any("error", err))
+			return nil, mcpErrorf("failed to scan row: %v", err)
+		}
+		result = append(result, row)
+	}
+	if err = rows.Err(); err != nil {
+		t.log.ErrorContext(ctx, "Error iterating rows", slog.Any("error", err))
+		return nil, mcpErrorf("failed to read rows: %v", err)
+	}
+
+	return result, nil
+}
+
+// queryTool executes a fixed, read-only query and returns the rows as a
+// JSON array.
+func (t *tools) queryTool(ctx context.Context, statement string, args ...any) (*mcp.CallToolResult, any, error) {
+	rows, errResult := t.query(ctx, statement, args...)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	return t.jsonResult(ctx, rows)
+}
+
+// jsonResult marshals v to JSON and returns it as the text content of a
+// tool result.
+func (t *tools) jsonResult(ctx context.Context, v any) (*mcp.CallToolResult, any, error) {
+	data, err := json.Marshal(v)
 	if err != nil {
 		t.log.ErrorContext(ctx, "Error marshaling results", slog.Any("error", err))
 		return mcpErrorf("failed to marshal result: %v", err), nil, nil
 	}
 
-	t.log.InfoContext(ctx, "Query executed successfully", slog.Int("row_count", len(result)))
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
-			&mcp.TextContent{Text: string(jsonRows)},
+			&mcp.TextContent{Text: string(data)},
 		},
 	}, nil, nil
 }
 
+// ndjsonText encodes rows as newline-delimited JSON with one object per line.
+func ndjsonText(rows []map[string]any) (string, error) {
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return "", err
+		}
+	}
+	return sb.String(), nil
+}
+
+// csvText encodes rows as CSV with a header row of the column names. NULL
+// values are written as empty fields.
+func csvText(columns []string, rows []map[string]any) (string, error) {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	if err := w.Write(columns); err != nil {
+		return "", err
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, c := range columns {
+			switch v := row[c].(type) {
+			case nil:
+				record[i] = ""
+			case string:
+				record[i] = v
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	return sb.String(), w.Error()
+}
+
+// scanRow scans the current row into a map keyed by column name. Byte
+// slices are converted to strings so that they marshal as text.
+func scanRow(rows *sql.Rows, columns []string) (map[string]any, error) {
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]any, len(columns))
+	for i, column := range columns {
+		val := values[i]
+		if b, ok := val.([]byte); ok {
+			row[column] = string(b)
+		} else {
+			row[column] = val
+		}
+	}
+	return row, nil
+}
+
+// rawJSONColumns replaces the named string columns, which are expected to
+// contain JSON text (e.g. from json_group_array), with json.RawMessage values
+// so that they are embedded in the output rather than double-encoded.
+func rawJSONColumns(rows []map[string]any, columns ...string) {
+	for _, row := range rows {
+		for _, c := range columns {
+			if s, ok := row[c].(string); ok {
+				row[c] = json.RawMessage(s)
+			}
+		}
+	}
+}
+
+// rowLimitError returns an error result indicating that the query produced
+// more rows than the configured limit. The row_count is the number of rows
+// that were scanned before giving up.
+func rowLimitError(rowCount, maxRows int) *mcp.CallToolResult {
+	data, _ := json.Marshal(map[string]any{
+		"error":     fmt.Sprintf("query result exceeds the limit of %d rows; add filters, aggregations, or a LIMIT clause", maxRows),
+		"row_count": rowCount,
+	})
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(data)},
+		},
+		IsError: true,
+	}
+}
+
 func mcpErrorf(format string, args ...interface{}) *mcp.CallToolResult {
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
@@ -0,0 +1,727 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/andrewkroh/fleetpkg-mcp/internal/database"
+
+	// Register SQLite database driver.
+	_ "modernc.org/sqlite"
+)
+
+// newTestTools returns tools backed by an empty in-memory database containing
+// all tables. The given statements are executed to seed the database.
+func newTestTools(t *testing.T, opts Options, seed ...string) *tools {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	// Each connection to :memory: is a distinct database.
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range database.Creates {
+		_, err = db.ExecContext(t.Context(), stmt)
+		require.NoError(t, err)
+	}
+	for _, stmt := range seed {
+		_, err = db.ExecContext(t.Context(), stmt)
+		require.NoError(t, err, stmt)
+	}
+
+	ptr := &atomic.Pointer[sql.DB]{}
+	ptr.Store(db)
+	return newTools(nil, ptr, slog.Default(), opts)
+}
+
+// connectTestClient registers all capabilities backed by the database of tl
+// on a new server and returns a client session connected to it.
+func connectTestClient(t *testing.T, tl *tools) *mcp.ClientSession {
+	t.Helper()
+
+	s := mcp.NewServer(&mcp.Implementation{Name: "fleetpkg-mcp"}, nil)
+	AddCapabilities(s, nil, tl.db, tl.log, tl.opts)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	ss, err := s.Connect(t.Context(), serverTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { ss.Close() })
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test"}, nil)
+	cs, err := client.Connect(t.Context(), clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { cs.Close() })
+	return cs
+}
+
+// insertIntegrationSQL returns a statement that inserts a minimal
+// integration row with the given id and name.
+func insertIntegrationSQL(id int, name string) string {
+	return fmt.Sprintf(`INSERT INTO integrations (id, name, dir_name, title, version, description, type,
+                          format_version, owner_github, owner_type, file_path)
+VALUES (%d, '%s', '%s', '%s', '1.0.0', 'The %s integration.', 'integration', '3.0.0',
+        'elastic/integrations', 'elastic', 'packages/%s')`, id, name, name, name, name, name)
+}
+
+// insertDataStreamSQL returns a statement that inserts a minimal data stream.
+func insertDataStreamSQL(id, integrationID int, name string) string {
+	return fmt.Sprintf(`INSERT INTO data_streams (id, integration_id, name, title, type, file_path)
+VALUES (%d, %d, '%s', '%s', 'logs', 'data_stream/%s')`, id, integrationID, name, name, name)
+}
+
+// insertFieldSQL returns statements that insert a field into a data stream.
+func insertFieldSQL(id, dataStreamID int, name, typ string) string {
+	return fmt.Sprintf(`INSERT INTO fields (id, name, type, file_path, line_number, col)
+VALUES (%d, '%s', '%s', 'fields/fields.yml', %d, 1);
+INSERT INTO data_stream_fields (data_stream_id, field_id, fields_file_name)
+VALUES (%d, %d, 'fields.yml')`, id, name, typ, id, dataStreamID, id)
+}
+
+// resultText returns the text of the first content item in a tool result.
+func resultText(t *testing.T, res *mcp.CallToolResult) string {
+	t.Helper()
+	require.NotNil(t, res)
+	require.NotEmpty(t, res.Content)
+	text, ok := res.Content[0].(*mcp.TextContent)
+	require.True(t, ok, "expected text content")
+	return text.Text
+}
+
+func TestGetSQLTablesMarkdown(t *testing.T) {
+	tl := newTools(database.Creates[:], &atomic.Pointer[sql.DB]{}, slog.Default(), Options{})
+
+	res, _, err := tl.getSQLTables(t.Context(), nil, GetSQLTablesArgs{Format: "markdown"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	blocks := strings.Split(resultText(t, res), "## ")[1:]
+	require.Len(t, blocks, len(database.Creates))
+	for _, block := range blocks {
+		assert.Contains(t, block, "| column | type | nullable |")
+		assert.Regexp(t, `(?m)^\| \w+ \| [A-Z]+ \| (yes|no) \|$`, block)
+	}
+	assert.Contains(t, blocks[0], "| id | INTEGER | no |")
+	assert.Contains(t, blocks[0], "| license | TEXT | yes |")
+
+	res, _, err = tl.getSQLTables(t.Context(), nil, GetSQLTablesArgs{Format: "yaml"})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
+
+func TestIsReady(t *testing.T) {
+	tl := newTools(nil, &atomic.Pointer[sql.DB]{}, slog.Default(), Options{})
+
+	res, _, err := tl.isReady(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	assert.False(t, res.IsError)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	assert.Equal(t, false, got["ready"])
+	assert.Contains(t, got, "elapsed_ms")
+}
+
+func TestReload(t *testing.T) {
+	release := make(chan struct{})
+	var reloads atomic.Int32
+	tl := newTools(nil, &atomic.Pointer[sql.DB]{}, slog.Default(), Options{
+		Reload: func() {
+			reloads.Add(1)
+			<-release
+		},
+	})
+
+	res, _, err := tl.reload(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `{"status":"reload_started"}`, resultText(t, res))
+
+	// A second call while the first reload is blocked does not start another.
+	res, _, err = tl.reload(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status":"reload_in_progress"}`, resultText(t, res))
+
+	close(release)
+	require.Eventually(t, func() bool { return !tl.reloading.Load() }, 5*time.Second, 10*time.Millisecond)
+	assert.EqualValues(t, 1, reloads.Load())
+
+	// Once the reload finishes a new one can be started.
+	res, _, err = tl.reload(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status":"reload_started"}`, resultText(t, res))
+	require.Eventually(t, func() bool { return reloads.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestCountStatements(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		want      int
+	}{
+		{name: "single", statement: "SELECT 1", want: 1},
+		{name: "trailing semicolon", statement: "SELECT 1;", want: 1},
+		{name: "trailing semicolons and whitespace", statement: "SELECT 1; ;\n", want: 1},
+		{name: "semicolon in string", statement: "SELECT 'a;b' FROM integrations", want: 1},
+		{name: "escaped quote in string", statement: "SELECT 'it''s; fine'", want: 1},
+		{name: "semicolon in identifier", statement: `SELECT "a;b" FROM integrations`, want: 1},
+		{name: "semicolon in line comment", statement: "SELECT 1 -- first; second\n", want: 1},
+		{name: "semicolon in block comment", statement: "SELECT /* ; */ 1", want: 1},
+		{name: "two statements", statement: "SELECT 1; SELECT 2", want: 2},
+		{name: "two terminated statements", statement: "SELECT 1; SELECT 2;", want: 2},
+		{name: "hidden second statement", statement: "SELECT ';'; DELETE FROM integrations", want: 2},
+		{name: "three statements", statement: "SELECT 1;SELECT 2;SELECT 3", want: 3},
+		{name: "empty", statement: "  ", want: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, countStatements(tc.statement))
+		})
+	}
+}
+
+func TestExecuteQueryParams(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+	)
+
+	run := func(args ExecuteQueryArgs) []map[string]any {
+		t.Helper()
+		res, _, err := tl.executeQuery(t.Context(), nil, args)
+		require.NoError(t, err)
+		require.False(t, res.IsError, resultText(t, res))
+		var got struct {
+			Rows []map[string]any `json:"rows"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+		return got.Rows
+	}
+
+	rows := run(ExecuteQueryArgs{Statement: "SELECT name FROM integrations WHERE name = ?", Params: []any{"nginx"}})
+	assert.Equal(t, []map[string]any{{"name": "nginx"}}, rows)
+
+	// An injection attempt is bound as a literal string.
+	rows = run(ExecuteQueryArgs{Statement: "SELECT name FROM integrations WHERE name = ?", Params: []any{"x' OR 1=1 --"}})
+	assert.Empty(t, rows)
+
+	// Params are combined with paging.
+	rows = run(ExecuteQueryArgs{
+		Statement: "SELECT name FROM integrations WHERE id >= ?1 ORDER BY name",
+		Params:    []any{1},
+		Limit:     1,
+		Offset:    1,
+	})
+	assert.Equal(t, []map[string]any{{"name": "nginx"}}, rows)
+}
+
+func TestExecuteQueryOutputFormat(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+	)
+
+	const statement = "SELECT id, name, NULL AS note FROM integrations ORDER BY id"
+
+	res, _, err := tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{Statement: statement, OutputFormat: "ndjson"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	lines := strings.Split(strings.TrimSuffix(resultText(t, res), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"id":1,"name":"nginx","note":null}`, lines[0])
+	assert.JSONEq(t, `{"id":2,"name":"apache","note":null}`, lines[1])
+
+	res, _, err = tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{Statement: statement, OutputFormat: "csv"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	lines = strings.Split(strings.TrimSuffix(resultText(t, res), "\n"), "\n")
+	assert.Equal(t, []string{"id,name,note", "1,nginx,", "2,apache,"}, lines)
+
+	res, _, err = tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{Statement: statement, OutputFormat: "xml"})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
+
+func TestExecuteQueryMaxResultBytes(t *testing.T) {
+	tl := newTestTools(t, Options{MaxResultBytes: 50},
+		insertIntegrationSQL(1, "nginx"),
+		insertIntegrationSQL(2, "apache"),
+		insertIntegrationSQL(3, "aws"),
+	)
+
+	// Each row is {"id":N,"name":"..."}, so only the first two fit in 50 bytes.
+	res, _, err := tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{Statement: "SELECT id, name FROM integrations ORDER BY id"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	var got struct {
+		Rows        []map[string]any `json:"rows"`
+		IsTruncated bool             `json:"is_truncated"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	assert.True(t, got.IsTruncated)
+	assert.Len(t, got.Rows, 2)
+
+	// Results within the limit are not marked.
+	res, _, err = tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{Statement: "SELECT id FROM integrations WHERE id = 1"})
+	require.NoError(t, err)
+	assert.NotContains(t, resultText(t, res), "is_truncated")
+
+	// The text formats are followed by a note.
+	res, _, err = tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{Statement: "SELECT id, name FROM integrations ORDER BY id", OutputFormat: "csv"})
+	require.NoError(t, err)
+	require.Len(t, res.Content, 2)
+	assert.Equal(t, "id,name\n1,nginx\n2,apache\n", res.Content[0].(*mcp.TextContent).Text)
+	assert.Contains(t, res.Content[1].(*mcp.TextContent).Text, "result truncated after 2 rows")
+}
+
+func TestExecuteQueryMultipleStatements(t *testing.T) {
+	tl := newTestTools(t, Options{}, insertIntegrationSQL(1, "nginx"))
+
+	res, _, err := tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{Statement: "SELECT name FROM integrations; SELECT 2"})
+	require.NoError(t, err)
+	require.True(t, res.IsError)
+	assert.Contains(t, resultText(t, res), "only one statement per call is allowed; received 2 statements")
+
+	res, _, err = tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{Statement: "SELECT name FROM integrations WHERE name != 'a;b';"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+}
+
+func TestCheckReadOnly(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		wantErr   bool
+	}{
+		{name: "select", statement: "SELECT * FROM integrations"},
+		{name: "lowercase select", statement: "select name from integrations"},
+		{name: "with", statement: "WITH x AS (SELECT 1) SELECT * FROM x"},
+		{name: "explain", statement: "EXPLAIN QUERY PLAN SELECT * FROM fields"},
+		{name: "leading whitespace", statement: "\n\t  SELECT 1"},
+		{name: "leading line comment", statement: "-- list integrations\nSELECT name FROM integrations"},
+		{name: "leading block comment", statement: "/* DROP TABLE x */ SELECT 1"},
+		{name: "comment marker in string", statement: "SELECT '--' FROM integrations"},
+		{name: "insert", statement: "INSERT INTO integrations (name) VALUES ('x')", wantErr: true},
+		{name: "update", statement: "UPDATE integrations SET name = 'x'", wantErr: true},
+		{name: "delete", statement: "DELETE FROM integrations", wantErr: true},
+		{name: "drop", statement: "DROP TABLE integrations", wantErr: true},
+		{name: "attach", statement: "ATTACH DATABASE 'other.db' AS other", wantErr: true},
+		{name: "pragma", statement: "PRAGMA journal_mode=DELETE", wantErr: true},
+		{name: "comment hiding drop", statement: "-- SELECT\nDROP TABLE integrations", wantErr: true},
+		{name: "block comment hiding drop", statement: "/* SELECT */ DROP TABLE integrations", wantErr: true},
+		{name: "keyword prefix", statement: "SELECTED", wantErr: true},
+		{name: "with delete", statement: "WITH x AS (SELECT 1) DELETE FROM integrations", wantErr: true},
+		{name: "with update", statement: "WITH x AS (SELECT 1) UPDATE integrations SET name = 'x'", wantErr: true},
+		{name: "with insert", statement: "WITH x AS (SELECT 1) INSERT INTO integrations (name) SELECT * FROM x", wantErr: true},
+		{name: "with replace", statement: "with x as (select 1) replace into integrations (name) select * from x", wantErr: true},
+		{name: "with delete after comment", statement: "WITH x AS (SELECT 1) /* SELECT */ DELETE FROM integrations", wantErr: true},
+		{name: "write keyword in string", statement: "SELECT name FROM integrations WHERE description LIKE '%delete%'"},
+		{name: "write keyword in identifier", statement: `SELECT "update" FROM (SELECT 1 AS "update")`},
+		{name: "replace function", statement: "SELECT replace(name, '_', '-') FROM integrations"},
+		{name: "write keyword in name", statement: "SELECT json_insert('{}', '$.a', 1) AS deleted_count"},
+		{name: "empty", statement: "  ", wantErr: true},
+		{name: "only comment", statement: "-- SELECT 1", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkReadOnly(tc.statement)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestExecuteQueryPagination(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "apache"),
+		insertIntegrationSQL(2, "aws"),
+		insertIntegrationSQL(3, "nginx"),
+	)
+
+	type pagedResult struct {
+		Rows    []map[string]any `json:"rows"`
+		Offset  int64            `json:"offset"`
+		Limit   int64            `json:"limit"`
+		HasMore bool             `json:"has_more"`
+	}
+
+	page := func(statement string, offset, limit int64) pagedResult {
+		t.Helper()
+		res, _, err := tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{
+			Statement: statement,
+			Offset:    offset,
+			Limit:     limit,
+		})
+		require.NoError(t, err)
+		require.False(t, res.IsError, resultText(t, res))
+
+		var got pagedResult
+		require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+		return got
+	}
+
+	const stmt = "SELECT name FROM integrations ORDER BY name;"
+
+	got := page(stmt, 0, 2)
+	require.Len(t, got.Rows, 2)
+	assert.Equal(t, "apache", got.Rows[0]["name"])
+	assert.True(t, got.HasMore)
+
+	got = page(stmt, 2, 2)
+	require.Len(t, got.Rows, 1)
+	assert.Equal(t, "nginx", got.Rows[0]["name"])
+	assert.False(t, got.HasMore)
+
+	// A statement with its own LIMIT clause is run as written.
+	got = page("SELECT name FROM integrations ORDER BY name LIMIT 1", 0, 2)
+	require.Len(t, got.Rows, 1)
+	assert.Equal(t, "apache", got.Rows[0]["name"])
+	assert.Zero(t, got.Limit)
+	assert.False(t, got.HasMore)
+
+	// So is an EXPLAIN statement.
+	got = page("EXPLAIN QUERY PLAN SELECT name FROM integrations", 0, 2)
+	assert.NotEmpty(t, got.Rows)
+	assert.Zero(t, got.Limit)
+
+	// Duplicate column names are not renamed.
+	got = page("SELECT a.name, b.name FROM integrations a JOIN integrations b ON a.id = b.id ORDER BY a.id", 0, 1)
+	require.Len(t, got.Rows, 1)
+	assert.Equal(t, map[string]any{"name": "apache"}, got.Rows[0])
+
+	// A LIMIT inside a trailing subquery does not disable paging.
+	got = page("SELECT name FROM integrations WHERE id IN (SELECT id FROM integrations LIMIT 3) ORDER BY name", 0, 2)
+	require.Len(t, got.Rows, 2)
+	assert.Equal(t, "aws", got.Rows[1]["name"])
+	assert.True(t, got.HasMore)
+
+	// Trailing comments do not swallow the closing parenthesis.
+	got = page("SELECT name FROM integrations ORDER BY name -- sorted", 2, 2)
+	require.Len(t, got.Rows, 1)
+	assert.Equal(t, "nginx", got.Rows[0]["name"])
+}
+
+func TestExecuteQueryTracing(t *testing.T) {
+	tl := newTestTools(t, Options{Tracer: noop.NewTracerProvider().Tracer("test")},
+		insertIntegrationSQL(1, "apache"),
+	)
+
+	res, _, err := tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{
+		Statement: "SELECT name FROM integrations",
+	})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.Contains(t, resultText(t, res), "apache")
+
+	res, _, err = tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{
+		Statement: "SELECT * FROM does_not_exist",
+	})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+}
+
+func TestExecuteQuerySpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { tp.Shutdown(t.Context()) })
+
+	tl := newTestTools(t, Options{Tracer: tp.Tracer("test")},
+		insertIntegrationSQL(1, "apache"),
+		insertIntegrationSQL(2, "nginx"),
+	)
+
+	const statement = "SELECT name FROM integrations"
+	res, _, err := tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{Statement: statement})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	res, _, err = tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{Statement: "SELECT * FROM does_not_exist"})
+	require.NoError(t, err)
+	require.True(t, res.IsError)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	attrs := func(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		m := map[attribute.Key]attribute.Value{}
+		for _, kv := range s.Attributes() {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+
+	assert.Equal(t, "executeQuery", spans[0].Name())
+	assert.Equal(t, statement, attrs(spans[0])["db.statement"].AsString())
+	assert.EqualValues(t, 2, attrs(spans[0])["db.row_count"].AsInt64())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	assert.Equal(t, "executeQuery", spans[1].Name())
+	assert.Equal(t, "SELECT * FROM does_not_exist", attrs(spans[1])["db.statement"].AsString())
+	assert.NotContains(t, attrs(spans[1]), attribute.Key("db.row_count"))
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
+
+func TestExecuteQueryTimeout(t *testing.T) {
+	// The query blocks until its context is cancelled.
+	db, err := sql.Open("slowdb", "1h")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	ptr := &atomic.Pointer[sql.DB]{}
+	ptr.Store(db)
+	tl := newTools(nil, ptr, slog.New(slog.DiscardHandler), Options{MaxQueryTime: 20 * time.Millisecond})
+
+	res, _, err := tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{Statement: "SELECT 1 AS n"})
+	require.NoError(t, err)
+	require.True(t, res.IsError)
+	assert.Contains(t, resultText(t, res), "query timed out after 20ms")
+}
+
+func TestExecuteQuerySlowQueryLog(t *testing.T) {
+	db, err := sql.Open("slowdb", "50ms")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	ptr := &atomic.Pointer[sql.DB]{}
+	ptr.Store(db)
+
+	run := func(threshold time.Duration) string {
+		t.Helper()
+		var buf bytes.Buffer
+		log := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		tl := newTools(nil, ptr, log, Options{SlowQueryThreshold: threshold})
+
+		res, _, err := tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{Statement: "SELECT 1 AS n"})
+		require.NoError(t, err)
+		require.False(t, res.IsError, resultText(t, res))
+		return buf.String()
+	}
+
+	out := run(10 * time.Millisecond)
+	assert.Contains(t, out, "level=WARN")
+	assert.Contains(t, out, `msg="Slow query"`)
+	assert.Contains(t, out, `statement="SELECT 1 AS n"`)
+	assert.Contains(t, out, "row_count=1")
+	assert.Regexp(t, `duration=\d+`, out)
+
+	assert.Empty(t, run(time.Minute))
+	assert.Empty(t, run(0))
+}
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := OpenAuditLog(path)
+	require.NoError(t, err)
+
+	tl := newTestTools(t, Options{AuditLog: audit},
+		insertIntegrationSQL(1, "apache"),
+		insertIntegrationSQL(2, "nginx"),
+	)
+	for _, stmt := range []string{
+		"SELECT name FROM integrations",
+		"SELECT * FROM does_not_exist",
+	} {
+		_, _, err = tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{Statement: stmt})
+		require.NoError(t, err)
+	}
+	require.NoError(t, audit.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var entries [2]map[string]any
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &entries[i]))
+		assert.NotEmpty(t, entries[i]["ts"])
+		assert.Contains(t, entries[i], "duration_ms")
+	}
+
+	assert.Equal(t, "SELECT name FROM integrations", entries[0]["statement"])
+	assert.EqualValues(t, 2, entries[0]["rows"])
+	assert.NotContains(t, entries[0], "error")
+
+	assert.Equal(t, "SELECT * FROM does_not_exist", entries[1]["statement"])
+	assert.EqualValues(t, 0, entries[1]["rows"])
+	assert.Contains(t, entries[1]["error"], "no such table")
+}
+
+func TestExecuteQuerySchema(t *testing.T) {
+	tl := newTestTools(t, Options{}, insertIntegrationSQL(1, "apache"))
+
+	res, _, err := tl.executeQuery(t.Context(), nil, ExecuteQueryArgs{
+		Statement: "SELECT id, name FROM integrations LIMIT 1",
+	})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	var got struct {
+		Schema []columnSchema   `json:"schema"`
+		Rows   []map[string]any `json:"rows"`
+		Limit  *int64           `json:"limit"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	assert.Equal(t, []columnSchema{
+		{Name: "id", Type: "INTEGER"},
+		{Name: "name", Type: "TEXT"},
+	}, got.Schema)
+	require.Len(t, got.Rows, 1)
+	assert.Equal(t, "apache", got.Rows[0]["name"])
+	assert.Nil(t, got.Limit)
+}
+
+func TestExplainQuery(t *testing.T) {
+	tl := newTestTools(t, Options{})
+
+	res, _, err := tl.explainQuery(t.Context(), nil, ExecuteQueryArgs{
+		Statement: "SELECT name FROM integrations WHERE title = 'nginx'",
+	})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	text := resultText(t, res)
+	assert.True(t, strings.HasPrefix(text, "id | parent | notused | detail\n"), text)
+	assert.Contains(t, text, "integrations")
+
+	res, _, err = tl.explainQuery(t.Context(), nil, ExecuteQueryArgs{
+		Statement: "DELETE FROM integrations",
+	})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+
+	// A trailing statement would be executed by the driver.
+	res, _, err = tl.explainQuery(t.Context(), nil, ExecuteQueryArgs{
+		Statement: "SELECT 1; DROP TABLE integrations",
+	})
+	require.NoError(t, err)
+	require.True(t, res.IsError)
+	assert.Contains(t, resultText(t, res), "only one statement per call is allowed")
+	_, errResult := tl.query(t.Context(), "SELECT count(*) FROM integrations")
+	assert.Nil(t, errResult)
+}
+
+func TestRunQueryPlan(t *testing.T) {
+	tl := newTestTools(t, Options{})
+
+	res, _, err := tl.runQueryPlan(t.Context(), nil, RunQueryPlanArgs{
+		Statement: "SELECT name FROM integrations WHERE title = 'nginx' ORDER BY version",
+	})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	text := resultText(t, res)
+	assert.True(t, strings.HasPrefix(text, "QUERY PLAN\n"), text)
+	assert.Contains(t, text, "SCAN integrations")
+
+	res, _, err = tl.runQueryPlan(t.Context(), nil, RunQueryPlanArgs{
+		Statement: "DELETE FROM integrations",
+	})
+	require.NoError(t, err)
+	assert.True(t, res.IsError)
+
+	// A trailing statement would be executed by the driver.
+	res, _, err = tl.runQueryPlan(t.Context(), nil, RunQueryPlanArgs{
+		Statement: "SELECT 1; DROP TABLE integrations",
+	})
+	require.NoError(t, err)
+	require.True(t, res.IsError)
+	assert.Contains(t, resultText(t, res), "only one statement per call is allowed")
+	_, errResult := tl.query(t.Context(), "SELECT count(*) FROM integrations")
+	assert.Nil(t, errResult)
+}
+
+func TestQueryPlanTree(t *testing.T) {
+	rows := []map[string]any{
+		{"id": int64(2), "parent": int64(0), "detail": "SCAN a"},
+		{"id": int64(5), "parent": int64(0), "detail": "CORRELATED SCALAR SUBQUERY 1"},
+		{"id": int64(8), "parent": int64(5), "detail": "SEARCH b USING INDEX b_idx (x=?)"},
+		{"id": int64(20), "parent": int64(0), "detail": "USE TEMP B-TREE FOR ORDER BY"},
+	}
+
+	const want = "QUERY PLAN\n" +
+		"|--SCAN a\n" +
+		"|--CORRELATED SCALAR SUBQUERY 1\n" +
+		"|  `--SEARCH b USING INDEX b_idx (x=?)\n" +
+		"`--USE TEMP B-TREE FOR ORDER BY\n"
+	assert.Equal(t, want, queryPlanTree(rows))
+}
+
+func TestGetTableRowCounts(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "apache"),
+		insertIntegrationSQL(2, "nginx"),
+	)
+
+	res, _, err := tl.getTableRowCounts(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	var got map[string]int64
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	assert.Len(t, got, len(database.TableNames))
+	assert.EqualValues(t, 2, got["integrations"])
+	assert.EqualValues(t, 0, got["fields"])
+}
+
+func TestGetDBStats(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "apache"),
+		insertIntegrationSQL(2, "nginx"),
+	)
+
+	res, _, err := tl.getDBStats(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	var got dbStats
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	assert.Empty(t, got.DBPath)
+	assert.Zero(t, got.FileSizeBytes)
+	require.Len(t, got.Tables, len(database.TableNames))
+	for _, table := range got.Tables {
+		if table.Name == "integrations" {
+			assert.EqualValues(t, 2, table.Rows)
+		}
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "fleetpkg.db")
+	require.NoError(t, os.WriteFile(dbPath, []byte("data"), 0o644))
+	tl.opts.DBPath = dbPath
+
+	res, _, err = tl.getDBStats(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	assert.Equal(t, dbPath, got.DBPath)
+	assert.EqualValues(t, 4, got.FileSizeBytes)
+}
@@ -0,0 +1,65 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func addPolicyTemplateTools(s *mcp.Server, t *tools) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_agentless_integrations",
+		Description: `Returns the policy templates that support agentless deployment as
+{integration_name, policy_template_name, is_default}. is_default is 1 when agentless
+is the default deployment mode for the policy template.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getAgentlessIntegrations)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_deployment_modes",
+		Description: `Returns every distinct combination of policy template deployment mode settings
+as {default_enabled, agentless_enabled, agentless_is_default, count}, where count is
+the number of policy templates using the combination. Policy templates that set no
+deployment modes are excluded. Sorted by count, most used first.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listDeploymentModes)
+}
+
+const agentlessIntegrationsQuery = `
+SELECT integrations.name                                                AS integration_name,
+       policy_templates.name                                            AS policy_template_name,
+       COALESCE(policy_templates.deployment_modes_agentless_is_default, 0) AS is_default
+FROM policy_templates
+         JOIN integrations ON integrations.id = policy_templates.integration_id
+WHERE policy_templates.deployment_modes_agentless_enabled = 1
+ORDER BY integrations.name, policy_templates.name`
+
+func (t *tools) getAgentlessIntegrations(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, agentlessIntegrationsQuery)
+}
+
+const listDeploymentModesQuery = `
+SELECT deployment_modes_default_enabled      AS default_enabled,
+       deployment_modes_agentless_enabled    AS agentless_enabled,
+       deployment_modes_agentless_is_default AS agentless_is_default,
+       COUNT(*)                              AS count
+FROM policy_templates
+WHERE deployment_modes_default_enabled IS NOT NULL
+   OR deployment_modes_agentless_enabled IS NOT NULL
+   OR deployment_modes_agentless_is_default IS NOT NULL
+GROUP BY default_enabled, agentless_enabled, agentless_is_default
+ORDER BY count DESC, default_enabled, agentless_enabled, agentless_is_default`
+
+func (t *tools) listDeploymentModes(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, listDeploymentModesQuery)
+}
@@ -0,0 +1,52 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAgentlessIntegrations(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "aws"),
+		insertIntegrationSQL(2, "nginx"),
+		`INSERT INTO policy_templates (integration_id, name, title, description,
+		                              deployment_modes_agentless_enabled, deployment_modes_agentless_is_default) VALUES
+			(1, 'cloudtrail', 'CloudTrail', 'Collect CloudTrail logs', 1, 1),
+			(1, 's3', 'S3', 'Collect S3 logs', 1, NULL),
+			(2, 'nginx', 'Nginx', 'Collect Nginx logs', 0, NULL)`,
+	)
+
+	res, _, err := tl.getAgentlessIntegrations(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"integration_name":"aws","policy_template_name":"cloudtrail","is_default":1},
+		{"integration_name":"aws","policy_template_name":"s3","is_default":0}
+	]`, resultText(t, res))
+}
+
+func TestListDeploymentModes(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "aws"),
+		`INSERT INTO policy_templates (integration_id, name, title, description, deployment_modes_default_enabled,
+		                              deployment_modes_agentless_enabled, deployment_modes_agentless_is_default) VALUES
+			(1, 'cloudtrail', 'CloudTrail', 'Collect CloudTrail logs', 1, 1, 1),
+			(1, 'guardduty', 'GuardDuty', 'Collect GuardDuty logs', 1, 1, 1),
+			(1, 's3', 'S3', 'Collect S3 logs', 0, 1, NULL),
+			(1, 'ec2', 'EC2', 'Collect EC2 metrics', NULL, NULL, NULL)`,
+	)
+
+	res, _, err := tl.listDeploymentModes(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[
+		{"default_enabled":1,"agentless_enabled":1,"agentless_is_default":1,"count":2},
+		{"default_enabled":0,"agentless_enabled":1,"agentless_is_default":null,"count":1}
+	]`, resultText(t, res))
+}
@@ -0,0 +1,116 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func addPrompts(s *mcp.Server) {
+	s.AddPrompt(&mcp.Prompt{
+		Name:        "analyze_integration",
+		Title:       "Analyze an integration",
+		Description: "Step-by-step analysis of one integration package: metadata, data streams, fields, pipelines, and variables.",
+		Arguments: []*mcp.PromptArgument{
+			{
+				Name:        "integration_name",
+				Description: "name of the integration package (e.g. nginx)",
+				Required:    true,
+			},
+		},
+	}, analyzeIntegrationPrompt)
+
+	s.AddPrompt(&mcp.Prompt{
+		Name:        "find_fields_for_query",
+		Title:       "Find fields for a query",
+		Description: "Finds the fields that hold the data described in natural language, using the database schema.",
+		Arguments: []*mcp.PromptArgument{
+			{
+				Name:        "description",
+				Description: "natural-language description of the data to find (e.g. source IP of firewall denies)",
+				Required:    true,
+			},
+		},
+	}, findFieldsForQueryPrompt)
+
+	s.AddPrompt(&mcp.Prompt{
+		Name:        "audit_secret_vars",
+		Title:       "Audit secret variables",
+		Description: "Guides a security review of sensitive configuration variables that are not marked as secret.",
+	}, auditSecretVarsPrompt)
+}
+
+func analyzeIntegrationPrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	name := req.Params.Arguments["integration_name"]
+	if name == "" {
+		return nil, fmt.Errorf("integration_name is required")
+	}
+
+	return promptResult("Analyze the "+name+" integration", fmt.Sprintf(`Analyze the %[1]q Fleet integration package using the fleetpkg tools.
+
+1. Call fleetpkg_get_integration with name %[1]q to read its metadata, categories,
+   policy templates, and data streams. If it is not found, use
+   fleetpkg_search_integrations to find the correct name.
+2. Call fleetpkg_get_readme to understand what the integration collects.
+3. For each data stream, call fleetpkg_get_data_streams and
+   fleetpkg_get_field_coverage to review its fields and how well the sample
+   event covers them.
+4. Call fleetpkg_get_ingest_pipeline for each data stream and summarize the
+   processors used. Note any grok or dissect parsing.
+5. Call fleetpkg_get_sql_tables, then use fleetpkg_execute_sql_query to list
+   the configuration variables and whether they are required or secret.
+
+Finish with a short summary of the integration's purpose, its data streams,
+and any quality issues you found.`, name)), nil
+}
+
+func findFieldsForQueryPrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	description := req.Params.Arguments["description"]
+	if description == "" {
+		return nil, fmt.Errorf("description is required")
+	}
+
+	return promptResult("Find fields for: "+description, fmt.Sprintf(`Find the integration fields that hold this data: %q
+
+1. Call fleetpkg_get_sql_tables with format "markdown" to learn the schema of
+   the fields, data_stream_fields, data_streams, and integrations tables.
+2. Call fleetpkg_search_fields with keywords from the description to find
+   candidate fields by name and description.
+3. Use fleetpkg_execute_sql_query to narrow the candidates by type and to find
+   which integrations and data streams define them. Prefer ECS fields
+   (external = 'ecs') over integration-specific fields.
+
+Answer with a table of field name, type, description, and the integrations
+that define it, followed by an example SQL query that selects them.`, description)), nil
+}
+
+func auditSecretVarsPrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return promptResult("Audit secret variables", `Perform a security review of integration configuration variables.
+
+1. Call fleetpkg_list_secret_vars to see the variables already marked secret.
+2. Call fleetpkg_get_sql_tables, then use fleetpkg_execute_sql_query to find
+   variables in the vars table where secret is not true but the name, title,
+   or type suggests a credential (for example type 'password', or names
+   containing password, token, secret, api_key, or private_key).
+3. Join through integration_vars, policy_template_vars,
+   policy_template_input_vars, and stream_vars to find the integration that
+   owns each suspicious variable.
+
+Report each variable that should be marked secret with its integration, file
+path, and line number, grouped by integration.`), nil
+}
+
+// promptResult returns a prompt result with a single user message.
+func promptResult(description, text string) *mcp.GetPromptResult {
+	return &mcp.GetPromptResult{
+		Description: description,
+		Messages: []*mcp.PromptMessage{
+			{Role: "user", Content: &mcp.TextContent{Text: text}},
+		},
+	}
+}
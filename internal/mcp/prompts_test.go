@@ -0,0 +1,43 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPrompts(t *testing.T) {
+	cs := connectTestClient(t, newTestTools(t, Options{}))
+
+	res, err := cs.ListPrompts(t.Context(), nil)
+	require.NoError(t, err)
+
+	var names []string
+	for _, p := range res.Prompts {
+		names = append(names, p.Name)
+	}
+	assert.ElementsMatch(t, []string{"analyze_integration", "find_fields_for_query", "audit_secret_vars"}, names)
+}
+
+func TestGetPrompt(t *testing.T) {
+	cs := connectTestClient(t, newTestTools(t, Options{}))
+
+	res, err := cs.GetPrompt(t.Context(), &mcp.GetPromptParams{
+		Name:      "analyze_integration",
+		Arguments: map[string]string{"integration_name": "nginx"},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.Messages, 1)
+	text, ok := res.Messages[0].Content.(*mcp.TextContent)
+	require.True(t, ok)
+	assert.Contains(t, text.Text, `"nginx"`)
+
+	_, err = cs.GetPrompt(t.Context(), &mcp.GetPromptParams{Name: "analyze_integration"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func addResources(s *mcp.Server, t *tools) {
+	s.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:  "package_file",
+		Title: "Package file",
+		Description: `A pipeline, fields, or manifest YAML file of an integration package. The path
+is the absolute file_path stored in the ingest_pipelines, fields, integrations, or
+data_streams table (e.g. file:///src/integrations/packages/nginx/manifest.yml).`,
+		MIMEType:    "text/yaml",
+		URITemplate: "file://{+path}",
+	}, t.readPackageFile)
+}
+
+// packageFileQuery checks that a path belongs to a pipeline, fields, or
+// manifest file known to the database so that arbitrary files cannot be read.
+const packageFileQuery = `
+SELECT 1 FROM ingest_pipelines WHERE file_path = ?1
+UNION ALL
+SELECT 1 FROM fields WHERE file_path = ?1
+UNION ALL
+SELECT 1 FROM integrations WHERE file_path = ?1
+UNION ALL
+SELECT 1 FROM data_streams WHERE file_path = ?1
+LIMIT 1`
+
+func (t *tools) readPackageFile(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || !filepath.IsAbs(u.Path) {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	path := filepath.Clean(u.Path)
+
+	db := t.db.Load()
+	if db == nil {
+		return nil, errors.New("database is still initializing, please retry in a moment")
+	}
+
+	var known int
+	if err = db.QueryRowContext(ctx, packageFileQuery, path).Scan(&known); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		t.log.ErrorContext(ctx, "error executing query", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to look up %s: %w", path, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{URI: uri, MIMEType: "text/yaml", Text: string(data)},
+		},
+	}, nil
+}
@@ -0,0 +1,42 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPackageFileResource(t *testing.T) {
+	dir := t.TempDir()
+	pipelinePath := filepath.Join(dir, "default.yml")
+	require.NoError(t, os.WriteFile(pipelinePath, []byte("description: Pipeline for nginx access logs\n"), 0o644))
+	otherPath := filepath.Join(dir, "secret.yml")
+	require.NoError(t, os.WriteFile(otherPath, []byte("password: hunter2\n"), 0o644))
+
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "nginx"),
+		insertDataStreamSQL(1, 1, "access"),
+		fmt.Sprintf(`INSERT INTO ingest_pipelines (id, data_stream_id, name, file_path) VALUES (1, 1, 'default', '%s')`, pipelinePath),
+	)
+
+	cs := connectTestClient(t, tl)
+
+	res, err := cs.ReadResource(t.Context(), &mcp.ReadResourceParams{URI: "file://" + pipelinePath})
+	require.NoError(t, err)
+	require.Len(t, res.Contents, 1)
+	assert.Equal(t, "text/yaml", res.Contents[0].MIMEType)
+	assert.Equal(t, "description: Pipeline for nginx access logs\n", res.Contents[0].Text)
+
+	// Files that are not in the database cannot be read.
+	_, err = cs.ReadResource(t.Context(), &mcp.ReadResourceParams{URI: "file://" + otherPath})
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"time"
+)
+
+func init() {
+	sql.Register("slowdb", slowDriver{})
+}
+
+// slowDriver is a database/sql driver whose queries return a single row with
+// a single "n" column after sleeping for the duration given as the DSN.
+type slowDriver struct{}
+
+func (slowDriver) Open(dsn string) (driver.Conn, error) {
+	delay, err := time.ParseDuration(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return slowConn{delay: delay}, nil
+}
+
+type slowConn struct {
+	delay time.Duration
+}
+
+func (c slowConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c slowConn) Close() error                              { return nil }
+func (c slowConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (c slowConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(c.delay):
+	}
+	return &slowRows{}, nil
+}
+
+type slowRows struct {
+	done bool
+}
+
+func (r *slowRows) Columns() []string { return []string{"n"} }
+func (r *slowRows) Close() error      { return nil }
+
+func (r *slowRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
@@ -0,0 +1,46 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func addTransformTools(s *mcp.Server, t *tools) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_transforms",
+		Description: `Returns the Elasticsearch transforms defined by integrations as
+{integration_name, transform_name, source_index, dest_index, description, frequency},
+sorted by integration and transform name. When a transform reads from several
+indices, source_index is the first one. Set integration_name to only return the
+transforms of one integration.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getTransforms)
+}
+
+type GetTransformsArgs struct {
+	IntegrationName string `json:"integration_name,omitempty" jsonschema:"optional name of an integration package to filter by (e.g. endpoint)"`
+}
+
+const getTransformsQuery = `
+SELECT integrations.name                 AS integration_name,
+       transforms.name                   AS transform_name,
+       transforms.transform_source_index AS source_index,
+       transforms.transform_dest_index   AS dest_index,
+       transforms.transform_description  AS description,
+       transforms.transform_frequency    AS frequency
+FROM transforms
+         JOIN integrations ON integrations.id = transforms.integration_id
+WHERE ?1 = '' OR integrations.name = ?1
+ORDER BY integrations.name, transforms.name`
+
+func (t *tools) getTransforms(ctx context.Context, req *mcp.CallToolRequest, args GetTransformsArgs) (*mcp.CallToolResult, any, error) {
+	return t.queryTool(ctx, getTransformsQuery, args.IntegrationName)
+}
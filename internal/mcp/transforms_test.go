@@ -0,0 +1,42 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTransforms(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "endpoint"),
+		insertIntegrationSQL(2, "nginx"),
+		`INSERT INTO transforms (integration_id, name, transform_source_index, transform_dest_index,
+		                        transform_description, transform_frequency, file_path) VALUES
+			(1, 'metadata_current', 'metrics-endpoint.metadata-*', 'metrics-endpoint.metadata_current_default',
+			 'Latest endpoint metadata', '10s', 'packages/endpoint/elasticsearch/transform/metadata_current'),
+			(2, 'latest', 'logs-nginx.access-*', 'logs-nginx_latest', NULL, NULL,
+			 'packages/nginx/elasticsearch/transform/latest')`,
+	)
+
+	res, _, err := tl.getTransforms(t.Context(), nil, GetTransformsArgs{IntegrationName: "endpoint"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.JSONEq(t, `[{
+		"integration_name": "endpoint",
+		"transform_name": "metadata_current",
+		"source_index": "metrics-endpoint.metadata-*",
+		"dest_index": "metrics-endpoint.metadata_current_default",
+		"description": "Latest endpoint metadata",
+		"frequency": "10s"
+	}]`, resultText(t, res))
+
+	res, _, err = tl.getTransforms(t.Context(), nil, GetTransformsArgs{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+	assert.Contains(t, resultText(t, res), `"transform_name":"latest"`)
+}
@@ -0,0 +1,144 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func addVarTools(s *mcp.Server, t *tools) {
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_list_secret_vars",
+		Description: `Audits the use of secret variables. Returns {"secret_vars": [...], "possible_unmarked_secrets": [...]}
+where secret_vars are variables marked "secret: true" and possible_unmarked_secrets are
+variables whose name contains password, secret, token, or key but that are not marked
+secret. Each entry contains integration_name, scope (integration, policy_template,
+policy_template_input, or stream), parent_name, var_name, type, file_path, and line_number.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.listSecretVars)
+
+	mcp.AddTool(s, &mcp.Tool{
+		Name: "fleetpkg_get_var_defaults",
+		Description: `Returns every configuration variable with its default value as
+{integration_name, scope, parent_name, var_name, var_type, default_value, required, secret},
+where scope is integration, policy_template, policy_template_input, or stream and
+default_value is the JSON default (null when there is none). Useful for explaining how
+an integration is configured. Set integration_name to only return the variables of one
+integration.`,
+		Annotations: &mcp.ToolAnnotations{
+			IdempotentHint: true,
+			ReadOnlyHint:   true,
+		},
+	}, t.getVarDefaults)
+}
+
+// varOwnersCTE maps each variable to the integration and the object that
+// declares it.
+const varOwnersCTE = `
+WITH var_owners AS (SELECT integration_vars.var_id, integrations.name AS integration_name,
+                           'integration' AS scope, integrations.name AS parent_name
+                    FROM integration_vars
+                             JOIN integrations ON integrations.id = integration_vars.integration_id
+                    UNION ALL
+                    SELECT policy_template_vars.var_id, integrations.name,
+                           'policy_template', policy_templates.name
+                    FROM policy_template_vars
+                             JOIN policy_templates ON policy_templates.id = policy_template_vars.policy_template_id
+                             JOIN integrations ON integrations.id = policy_templates.integration_id
+                    UNION ALL
+                    SELECT policy_template_input_vars.var_id, integrations.name,
+                           'policy_template_input', policy_templates.name || '/' || policy_template_inputs.type
+                    FROM policy_template_input_vars
+                             JOIN policy_template_inputs
+                                  ON policy_template_inputs.id = policy_template_input_vars.policy_template_input_id
+                             JOIN policy_templates ON policy_templates.id = policy_template_inputs.policy_template_id
+                             JOIN integrations ON integrations.id = policy_templates.integration_id
+                    UNION ALL
+                    SELECT stream_vars.var_id, integrations.name,
+                           'stream', data_streams.name || '/' || streams.input
+                    FROM stream_vars
+                             JOIN streams ON streams.id = stream_vars.stream_id
+                             JOIN data_streams ON data_streams.id = streams.data_stream_id
+                             JOIN integrations ON integrations.id = data_streams.integration_id)`
+
+// varOwnersQuery lists each variable with its owner and location.
+const varOwnersQuery = varOwnersCTE + `
+SELECT var_owners.integration_name,
+       var_owners.scope,
+       var_owners.parent_name,
+       vars.name AS var_name,
+       vars.type,
+       vars.file_path,
+       vars.line_number
+FROM vars
+         JOIN var_owners ON var_owners.var_id = vars.id`
+
+const (
+	secretVarsQuery = varOwnersQuery + `
+WHERE vars.secret = 1
+ORDER BY var_owners.integration_name, vars.file_path, vars.line_number`
+
+	possibleUnmarkedSecretsQuery = varOwnersQuery + `
+WHERE COALESCE(vars.secret, 0) != 1
+  AND (vars.name LIKE '%password%'
+    OR vars.name LIKE '%secret%'
+    OR vars.name LIKE '%token%'
+    OR vars.name LIKE '%key%')
+ORDER BY var_owners.integration_name, vars.file_path, vars.line_number`
+)
+
+type secretVarsResult struct {
+	SecretVars              []map[string]any `json:"secret_vars"`
+	PossibleUnmarkedSecrets []map[string]any `json:"possible_unmarked_secrets"`
+}
+
+func (t *tools) listSecretVars(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	secrets, errResult := t.query(ctx, secretVarsQuery)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	unmarked, errResult := t.query(ctx, possibleUnmarkedSecretsQuery)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	return t.jsonResult(ctx, secretVarsResult{
+		SecretVars:              secrets,
+		PossibleUnmarkedSecrets: unmarked,
+	})
+}
+
+type GetVarDefaultsArgs struct {
+	IntegrationName string `json:"integration_name,omitempty" jsonschema:"optional name of an integration package to filter by (e.g. aws)"`
+}
+
+const varDefaultsQuery = varOwnersCTE + `
+SELECT var_owners.integration_name,
+       var_owners.scope,
+       var_owners.parent_name,
+       vars.name          AS var_name,
+       vars.type          AS var_type,
+       vars.default_value,
+       vars.required,
+       vars.secret
+FROM vars
+         JOIN var_owners ON var_owners.var_id = vars.id
+WHERE ?1 = '' OR var_owners.integration_name = ?1
+ORDER BY var_owners.integration_name, vars.file_path, vars.line_number`
+
+func (t *tools) getVarDefaults(ctx context.Context, req *mcp.CallToolRequest, args GetVarDefaultsArgs) (*mcp.CallToolResult, any, error) {
+	rows, errResult := t.query(ctx, varDefaultsQuery, args.IntegrationName)
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	rawJSONColumns(rows, "default_value")
+	return t.jsonResult(ctx, rows)
+}
@@ -0,0 +1,91 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListSecretVars(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "aws"),
+		`INSERT INTO policy_templates (id, integration_id, name, title, description) VALUES (1, 1, 'cloudtrail', 'CloudTrail', 'CloudTrail logs')`,
+		insertDataStreamSQL(1, 1, "cloudtrail"),
+		`INSERT INTO streams (id, data_stream_id, input, title, description) VALUES (1, 1, 'aws-s3', 'S3', 'Collect from S3')`,
+		`INSERT INTO vars (id, name, type, secret, file_path, line_number, col) VALUES
+			(1, 'secret_access_key', 'password', 1, 'manifest.yml', 10, 1),
+			(2, 'session_token', 'text', NULL, 'manifest.yml', 20, 1),
+			(3, 'bucket_arn', 'text', NULL, 'data_stream/cloudtrail/manifest.yml', 5, 1),
+			(4, 'api_password', 'password', 0, 'data_stream/cloudtrail/manifest.yml', 9, 1)`,
+		`INSERT INTO integration_vars (integration_id, var_id) VALUES (1, 1)`,
+		`INSERT INTO policy_template_vars (policy_template_id, var_id) VALUES (1, 2)`,
+		`INSERT INTO stream_vars (stream_id, var_id) VALUES (1, 3), (1, 4)`,
+	)
+
+	res, _, err := tl.listSecretVars(t.Context(), nil, struct{}{})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	var got secretVarsResult
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+
+	require.Len(t, got.SecretVars, 1)
+	assert.Equal(t, "secret_access_key", got.SecretVars[0]["var_name"])
+	assert.Equal(t, "integration", got.SecretVars[0]["scope"])
+	assert.Equal(t, "aws", got.SecretVars[0]["parent_name"])
+
+	require.Len(t, got.PossibleUnmarkedSecrets, 2)
+	assert.Equal(t, "api_password", got.PossibleUnmarkedSecrets[0]["var_name"])
+	assert.Equal(t, "stream", got.PossibleUnmarkedSecrets[0]["scope"])
+	assert.Equal(t, "cloudtrail/aws-s3", got.PossibleUnmarkedSecrets[0]["parent_name"])
+	assert.Equal(t, "session_token", got.PossibleUnmarkedSecrets[1]["var_name"])
+	assert.Equal(t, "policy_template", got.PossibleUnmarkedSecrets[1]["scope"])
+}
+
+func TestGetVarDefaults(t *testing.T) {
+	tl := newTestTools(t, Options{},
+		insertIntegrationSQL(1, "aws"),
+		insertIntegrationSQL(2, "nginx"),
+		insertDataStreamSQL(1, 1, "cloudtrail"),
+		`INSERT INTO streams (id, data_stream_id, input, title, description) VALUES (1, 1, 'aws-s3', 'S3', 'Collect from S3')`,
+		`INSERT INTO vars (id, name, type, default_value, required, secret, file_path, line_number, col) VALUES
+			(1, 'secret_access_key', 'password', NULL, 0, 1, 'manifest.yml', 10, 1),
+			(2, 'interval', 'text', '"1m"', 1, NULL, 'data_stream/cloudtrail/manifest.yml', 5, 1),
+			(3, 'paths', 'text', '["/var/log/nginx/access.log*"]', 1, NULL, 'manifest.yml', 3, 1)`,
+		`INSERT INTO integration_vars (integration_id, var_id) VALUES (1, 1), (2, 3)`,
+		`INSERT INTO stream_vars (stream_id, var_id) VALUES (1, 2)`,
+	)
+
+	res, _, err := tl.getVarDefaults(t.Context(), nil, GetVarDefaultsArgs{IntegrationName: "aws"})
+	require.NoError(t, err)
+	require.False(t, res.IsError, resultText(t, res))
+
+	var got []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	require.Len(t, got, 2)
+
+	assert.Equal(t, "interval", got[0]["var_name"])
+	assert.Equal(t, "stream", got[0]["scope"])
+	assert.Equal(t, "cloudtrail/aws-s3", got[0]["parent_name"])
+	assert.Equal(t, "1m", got[0]["default_value"])
+	assert.EqualValues(t, 1, got[0]["required"])
+
+	assert.Equal(t, "secret_access_key", got[1]["var_name"])
+	assert.Equal(t, "integration", got[1]["scope"])
+	assert.Equal(t, "password", got[1]["var_type"])
+	assert.Nil(t, got[1]["default_value"])
+	assert.EqualValues(t, 1, got[1]["secret"])
+
+	// Without a filter the variables of all integrations are returned.
+	res, _, err = tl.getVarDefaults(t.Context(), nil, GetVarDefaultsArgs{})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, res)), &got))
+	require.Len(t, got, 3)
+	assert.Equal(t, []any{"/var/log/nginx/access.log*"}, got[2]["default_value"])
+}
@@ -0,0 +1,70 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+// Package metrics defines the Prometheus metrics exported by fleetpkg-mcp.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	queriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "fleetpkg_queries_total",
+		Help: "Number of SQL queries executed, partitioned by status.",
+	}, []string{"status"})
+
+	queryDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "fleetpkg_query_duration_seconds",
+		Help:    "Duration of SQL query execution.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	dbReady = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "fleetpkg_db_ready",
+		Help: "Whether the database is loaded and ready for queries (1) or not (0).",
+	})
+
+	dbInitDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "fleetpkg_db_init_duration_seconds",
+		Help: "Duration of the most recent database initialization.",
+	})
+)
+
+func init() {
+	registry.MustRegister(queriesTotal, queryDuration, dbReady, dbInitDuration)
+
+	// Initialize both label values so the series are present before the
+	// first query.
+	queriesTotal.WithLabelValues("success")
+	queriesTotal.WithLabelValues("error")
+}
+
+// ObserveQuery records the outcome and duration of a query.
+func ObserveQuery(d time.Duration, failed bool) {
+	status := "success"
+	if failed {
+		status = "error"
+	}
+	queriesTotal.WithLabelValues(status).Inc()
+	queryDuration.Observe(d.Seconds())
+}
+
+// SetDBReady records that the database finished initializing after d.
+func SetDBReady(d time.Duration) {
+	dbReady.Set(1)
+	dbInitDuration.Set(d.Seconds())
+}
+
+// Handler returns an http.Handler that serves the metrics in the Prometheus
+// text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -7,25 +7,31 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
-	"net"
-	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"runtime"
 	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/andrewkroh/go-fleetpkg"
 	"github.com/gorilla/handlers"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/andrewkroh/fleetpkg-mcp/internal/fleetsql"
 	fleetmcp "github.com/andrewkroh/fleetpkg-mcp/internal/mcp"
+	"github.com/andrewkroh/fleetpkg-mcp/internal/metrics"
 
 	// Register SQLite database driver.
 	_ "modernc.org/sqlite"
@@ -33,36 +39,128 @@ import (
 
 var (
 	httpAddr        = flag.String("http", "", "listen for HTTP at this address, instead of stdin/stdout")
+	unixSocket      = flag.String("unix-socket", "", "listen for HTTP on a Unix domain socket at this path, instead of stdin/stdout")
+	apiKey          = flag.String("api-key", "", "require this Bearer token on all HTTP requests")
+	corsOrigins     = flag.String("cors-origins", "", "comma-separated list of origins allowed to make cross-origin HTTP requests, or *")
+	auditLogPath    = flag.String("audit-log", "", "append a JSON line for every SQL query executed to this file")
+	slowQueryMS     = flag.Int("slow-query-ms", 0, "log queries that take longer than this many milliseconds at WARN level (0 disables)")
+	otelEndpoint    = flag.String("otel-endpoint", "", "export OpenTelemetry traces to this OTLP gRPC endpoint")
+	tlsCert         = flag.String("tls-cert", "", "path to a PEM certificate file used to serve HTTPS (requires -tls-key)")
+	tlsKey          = flag.String("tls-key", "", "path to a PEM private key file used to serve HTTPS (requires -tls-cert)")
 	noLog           = flag.Bool("no-log", false, "if set, disables logging")
 	logLevel        = flag.String("log-level", "info", "log level (debug, info, warn, error)")
-	integrationsDir = flag.String("dir", "", "path to elastic/integrations directory")
+	logFormat       = flag.String("log-format", "text", "log format (text, json)")
+	logFile         = flag.String("log-file", "", "also write logs to this file (use '-' for stderr only)")
+	dbPath          = flag.String("db-path", "fleetpkg.db", "path where the SQLite database file is written")
+	continueOnError = flag.Bool("continue-on-error", false, "skip packages that fail to load instead of aborting")
+	strict          = flag.Bool("strict", false, "fail if a field is defined in more than one fields file of a data stream instead of logging a warning")
+	inMemory        = flag.Bool("in-memory", false, "keep the SQLite database in memory instead of writing it to disk")
+	watch           = flag.Bool("watch", false, "reload the database when files in the integrations packages directory change")
+	waitInit        = flag.Bool("wait-for-init", false, "do not start serving MCP requests until the database is initialized")
+	dbMaxConns      = flag.Int("db-max-conns", 10, "maximum number of open database connections (0 for unlimited)")
+	dbWorkers       = flag.Int("db-workers", max(1, runtime.NumCPU()/2), "number of packages written to the database concurrently")
+	maxRows         = flag.Int("max-rows", 10000, "maximum number of rows returned by a SQL query (0 for unlimited)")
+	maxResultBytes  = flag.Int("max-result-bytes", 1<<20, "maximum size in bytes of the rows returned by a SQL query; larger results are truncated (0 for unlimited)")
+	maxQueryTime    = flag.Duration("max-query-time", 30*time.Second, "maximum time a SQL query may run before it is cancelled (0 for unlimited)")
 	version         = flag.Bool("version", false, "print version and exit")
+	configPath      = flag.String("config", "", "path to a YAML config file whose keys are flag names; command line flags take precedence")
+	packageFilter   = flag.String("package-filter", "", "only load packages whose directory name matches this glob pattern (e.g. aws_*)")
+
+	// Repeatable flags registered in init.
+	integrationsDirs stringsFlag
+	skipPackages     stringsFlag
+	packagePaths     stringsFlag
 )
 
+func init() {
+	flag.Var(&integrationsDirs, "dir", "path to an elastic/integrations directory (may be repeated)")
+	flag.Var(&skipPackages, "skip-package", "name of a package directory to exclude from loading (may be repeated)")
+	flag.Var(&packagePaths, "pkg", "path to a single integration package directory to load (may be repeated)")
+}
+
+// stringsFlag is a flag.Value that collects the values of a repeated flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 func main() {
-	flag.Parse()
+	if err := setupFlags(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(2)
+	}
 
 	if *version {
 		fmt.Println(buildVersion())
 		return
 	}
 
-	if *integrationsDir == "" {
-		fmt.Fprintln(os.Stderr, "ERROR: -dir flag is required")
+	if len(integrationsDirs) == 0 && len(packagePaths) == 0 {
+		fmt.Fprintln(os.Stderr, "ERROR: -dir or -pkg flag is required")
 		os.Exit(2)
 	}
 
-	if err := run(*integrationsDir); err != nil {
+	// fleetpkg-mcp -dir <path> query <statement>
+	if args := flag.Args(); len(args) > 0 && args[0] == "query" {
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "ERROR: usage: fleetpkg-mcp -dir <path> query <statement>")
+			os.Exit(2)
+		}
+		if err := query(integrationsDirs, args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := run(integrationsDirs); err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func run(integrationsDir string) error {
+func run(integrationsDirs []string) error {
+	if (*tlsCert == "") != (*tlsKey == "") {
+		return errors.New("-tls-cert and -tls-key must be used together")
+	}
+	if *httpAddr != "" && *unixSocket != "" {
+		return errors.New("-http and -unix-socket cannot be used together")
+	}
+
+	// File paths stored in the database are exposed as file:// resource URIs,
+	// so they must be absolute.
+	for i, dir := range integrationsDirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve integrations directory: %w", err)
+		}
+		integrationsDirs[i] = abs
+	}
+	for i, pkgPath := range packagePaths {
+		abs, err := filepath.Abs(pkgPath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve package path: %w", err)
+		}
+		packagePaths[i] = abs
+	}
+
 	// Set up logging.
 	var logOutput io.Writer = os.Stderr
 	if *noLog {
 		logOutput = io.Discard
+	} else if *logFile != "" && *logFile != "-" {
+		f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		defer f.Close()
+		logOutput = io.MultiWriter(os.Stderr, f)
 	}
 
 	log, err := logger(logOutput)
@@ -71,8 +169,12 @@ func run(integrationsDir string) error {
 	}
 	slog.SetDefault(log)
 
-	modVer, vcsRef := buildVersion()
-	log.Info("fleetpkg-mcp is starting...", slog.String("version", modVer), slog.String("vcs_ref", vcsRef))
+	ver := buildVersion()
+	log.Info("fleetpkg-mcp is starting...",
+		slog.String("version", ver.Version),
+		slog.String("vcs_ref", ver.VCSRef),
+		slog.String("build_date", ver.BuildDate),
+		slog.String("go_version", ver.GoVersion))
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
@@ -84,41 +186,127 @@ func run(integrationsDir string) error {
 	s := mcp.NewServer(&mcp.Implementation{
 		Name:    "fleetpkg",
 		Title:   "Elastic Fleet Integration Package metadata MCP server",
-		Version: modVer + " (" + vcsRef + ")",
+		Version: ver.Version + " (" + ver.VCSRef + ")",
 	}, nil)
-	fleetmcp.AddTools(s, fleetsql.TableSchemas(), dbPtr, log)
+
+	// Tracing is only configured when an endpoint is given.
+	var tracer trace.Tracer
+	if *otelEndpoint != "" {
+		tp, err := newTracerProvider(ctx, *otelEndpoint, ver.Version)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := tp.Shutdown(context.Background()); err != nil {
+				log.Warn("Failed to shutdown tracer provider", slog.Any("error", err))
+			}
+		}()
+		tracer = tp.Tracer("github.com/andrewkroh/fleetpkg-mcp")
+	}
+
+	var auditLog *fleetmcp.AuditLog
+	if *auditLogPath != "" {
+		if auditLog, err = fleetmcp.OpenAuditLog(*auditLogPath); err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		defer func() {
+			if err := auditLog.Close(); err != nil {
+				log.Warn("Failed to close audit log", slog.Any("error", err))
+			}
+		}()
+	}
+
+	loader := &databaseLoader{
+		log:              log,
+		integrationsDirs: integrationsDirs,
+		opts:             dbOptionsFromFlags(),
+		db:               dbPtr,
+		tracer:           tracer,
+		closeDelay:       reloadCloseDelay,
+	}
+
+	fleetmcp.AddCapabilities(s, fleetsql.TableSchemas(), dbPtr, log, fleetmcp.Options{
+		MaxRows:            *maxRows,
+		SlowQueryThreshold: time.Duration(*slowQueryMS) * time.Millisecond,
+		MaxQueryTime:       *maxQueryTime,
+		MaxResultBytes:     *maxResultBytes,
+		AuditLog:           auditLog,
+		Tracer:             tracer,
+		DBPath:             reportedDBPath(),
+		Reload: func() {
+			log.Info("Reload requested by fleetpkg_reload")
+			_ = loader.reload(ctx)
+		},
+	})
+
+	// Rebuild the database on SIGHUP.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				log.Info("Received SIGHUP")
+				_ = loader.reload(ctx)
+			}
+		}
+	}()
+
+	if *watch {
+		go func() {
+			if err := loader.watch(ctx, watchDebounce); err != nil {
+				log.Error("File watcher failed", slog.Any("error", err))
+			}
+		}()
+	}
 
 	// Start initialization in background
 	initErrCh := make(chan error, 1)
 	go func() {
 		start := time.Now()
 		log.Info("Starting database initialization...")
-		db, err := initializeDatabase(ctx, log, integrationsDir)
+		db, err := loader.build(ctx)
 		if err != nil {
 			log.Error("Database initialization failed", slog.Any("error", err))
 			initErrCh <- err
 			return
 		}
 		dbPtr.Store(db)
+		metrics.SetDBReady(time.Since(start))
 		log.Info("Database initialization completed", slog.Duration("duration", time.Since(start)))
 		close(initErrCh)
 	}()
 
 	// Listen over HTTP.
-	if *httpAddr != "" {
-		var handler http.Handler = mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server { return s }, nil)
+	if *httpAddr != "" || *unixSocket != "" {
+		handler := httpMiddleware(newHTTPHandler(s, dbPtr), *apiKey, *corsOrigins)
 
-		listener, err := net.Listen("tcp", *httpAddr)
+		listener, err := listenWhenReady(ctx, initErrCh, *waitInit, *httpAddr, *unixSocket)
 		if err != nil {
-			return fmt.Errorf("failed to listen on %q: %w", *httpAddr, err)
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
 		}
+		// Closing the listener also removes the Unix socket file.
+		defer listener.Close()
 		go func() {
 			<-ctx.Done()
 			listener.Close()
 		}()
 
-		log.Info("fleetpkg-mcp handler listening",
-			slog.String("addr", "http://"+listener.Addr().String()))
+		scheme := "http://"
+		if *tlsCert != "" {
+			scheme = "https://"
+		}
+		addr := scheme + listener.Addr().String()
+		if *unixSocket != "" {
+			addr = "unix:" + listener.Addr().String()
+		}
+		log.Info("fleetpkg-mcp handler listening", slog.String("addr", addr))
 
 		if !*noLog {
 			handler = handlers.CombinedLoggingHandler(os.Stdout, handler)
@@ -127,7 +315,7 @@ func run(integrationsDir string) error {
 		// Serve HTTP in goroutine
 		serveDone := make(chan error, 1)
 		go func() {
-			serveDone <- http.Serve(listener, handler)
+			serveDone <- serveHTTP(listener, handler, *tlsCert, *tlsKey)
 		}()
 
 		// Wait for context cancellation, init error, or serve error
@@ -145,7 +333,16 @@ func run(integrationsDir string) error {
 		}
 	}
 
-	// Stdin/stdout comms - also start immediately
+	// Stdin/stdout comms - also start immediately unless waiting for the
+	// database.
+	if *waitInit {
+		if err := waitForInit(ctx, initErrCh); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
 	serveDone := make(chan error, 1)
 	go func() {
 		t := &mcp.LoggingTransport{
@@ -173,91 +370,380 @@ func run(integrationsDir string) error {
 	}
 }
 
+// waitForInit blocks until the database initialization that reports to
+// initErrCh has finished or ctx is done. The channel is closed on success.
+func waitForInit(ctx context.Context, initErrCh <-chan error) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-initErrCh:
+		if err != nil {
+			return fmt.Errorf("initialization failed: %w", err)
+		}
+		return nil
+	}
+}
+
+// query builds the database and writes the result of the statement to stdout
+// as tab-separated values.
+func query(integrationsDirs []string, statement string) error {
+	var logOutput io.Writer = os.Stderr
+	if *noLog {
+		logOutput = io.Discard
+	}
+	log, err := logger(logOutput)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	return runQuery(ctx, log, os.Stdout, integrationsDirs, dbOptionsFromFlags(), statement)
+}
+
+// reportedDBPath returns the database file path, or an empty string when the
+// database is kept in memory.
+func reportedDBPath() string {
+	if *inMemory {
+		return ""
+	}
+	return *dbPath
+}
+
+// dbOptionsFromFlags returns the database options set by command line flags.
+func dbOptionsFromFlags() dbOptions {
+	return dbOptions{
+		path:     *dbPath,
+		inMemory: *inMemory,
+		maxConns: *dbMaxConns,
+		workers:  *dbWorkers,
+		strict:   *strict,
+		load: loadOptions{
+			continueOnError: *continueOnError,
+			skipPackages:    skipPackages,
+			packageFilter:   *packageFilter,
+			packagePaths:    packagePaths,
+		},
+	}
+}
+
 func logger(sink io.Writer) (*slog.Logger, error) {
 	level := new(slog.LevelVar)
 	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
 		return nil, err
 	}
 
-	return slog.New(
-		slog.NewTextHandler(
-			sink,
-			&slog.HandlerOptions{
-				Level: level,
-			},
-		)), nil
+	opts := &slog.HandlerOptions{
+		Level: level,
+	}
+
+	switch *logFormat {
+	case "text":
+		return slog.New(slog.NewTextHandler(sink, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(sink, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q (must be text or json)", *logFormat)
+	}
+}
+
+// versionInfo describes the build of the running binary.
+type versionInfo struct {
+	Version   string // Main module version.
+	VCSRef    string // VCS revision the binary was built from.
+	BuildDate string // VCS commit time of the revision.
+	GoVersion string // Go toolchain used to build the binary.
 }
 
-func buildVersion() (modVersion, vcsRef string) {
+func (v versionInfo) String() string {
+	return fmt.Sprintf("%s (%s, built %s, %s)", v.Version, v.VCSRef, v.BuildDate, v.GoVersion)
+}
+
+func buildVersion() versionInfo {
 	info, ok := debug.ReadBuildInfo()
 	if !ok {
-		return "", ""
+		return versionInfo{}
 	}
 
+	v := versionInfo{
+		Version:   info.Main.Version,
+		GoVersion: info.GoVersion,
+	}
 	for _, setting := range info.Settings {
-		if setting.Key == "vcs.revision" {
-			vcsRef = setting.Value
-			break
+		switch setting.Key {
+		case "vcs.revision":
+			v.VCSRef = setting.Value
+		case "vcs.time":
+			v.BuildDate = setting.Value
 		}
 	}
 
-	return info.Main.Version, vcsRef
+	return v
+}
+
+// dbOptions controls how initializeDatabase builds the database.
+type dbOptions struct {
+	path     string      // Path of the SQLite database file. Any existing file is replaced.
+	inMemory bool        // Keep the database in memory and never write it to disk.
+	maxConns int         // Maximum number of open connections. Zero means no limit.
+	workers  int         // Number of packages written concurrently.
+	strict   bool        // Fail on fields defined in more than one fields file of a data stream.
+	load     loadOptions // Options for reading packages.
+}
+
+// loadOptions controls how loadPackages reads packages.
+type loadOptions struct {
+	continueOnError bool     // Skip packages that fail to load instead of returning an error.
+	skipPackages    []string // Package directory names to exclude.
+	packageFilter   string   // Glob pattern that package directory names must match.
+	packagePaths    []string // Package directories loaded in addition to those in the integrations directories.
 }
 
 // initializeDatabase loads packages and creates a read-only SQLite database.
-func initializeDatabase(ctx context.Context, log *slog.Logger, integrationsDir string) (*sql.DB, error) {
+// When opts.inMemory is true the database is never written to disk and the
+// returned *sql.DB has query_only enabled on each of its connections.
+func initializeDatabase(ctx context.Context, log *slog.Logger, integrationsDirs []string, opts dbOptions) (*sql.DB, error) {
+	dbPath := opts.path
+
+	writeOpts := fleetsql.WriteOptions{
+		Workers: opts.workers,
+		Strict:  opts.strict,
+		Log:     log,
+	}
+
 	// Read packages from the integrations repo.
-	pkgs, err := loadPackages(log, integrationsDir)
+	pkgs, err := loadPackages(log, integrationsDirs, opts.load)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load packages: %w", err)
 	}
 
-	// Create a new DB.
-	if err = os.Remove("fleetpkg.db"); err != nil && !os.IsNotExist(err) {
+	if opts.inMemory {
+		dsn := fleetsql.InMemoryDSN()
+		db, err := sql.Open("sqlite", dsn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open in-memory database: %w", err)
+		}
+		if err = fleetsql.WritePackages(ctx, db, pkgs, writeOpts); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to write packages to DB: %w", err)
+		}
+
+		// Reopen the shared in-memory database with query_only set on every
+		// connection. An in-memory database disappears when its last
+		// connection is closed so a read connection is opened before the
+		// writer is closed.
+		ro, err := sql.Open("sqlite", dsn+"&_pragma=query_only(1)")
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open in-memory database read-only: %w", err)
+		}
+		if err = ro.PingContext(ctx); err != nil {
+			ro.Close()
+			db.Close()
+			return nil, fmt.Errorf("failed to open in-memory database read-only: %w", err)
+		}
+		if err = db.Close(); err != nil {
+			ro.Close()
+			return nil, fmt.Errorf("failed to close database: %w", err)
+		}
+		setConnPool(ro, opts.maxConns)
+		return ro, nil
+	}
+
+	// Create a new DB. It is written to a temporary file and then renamed
+	// into place so that a database that is still being served (e.g. during
+	// a reload) is never observed in a partially written state.
+	tmpPath := dbPath + ".tmp"
+	if err = os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("failed to remove existing database: %w", err)
 	}
-	db, err := sql.Open("sqlite", "file:fleetpkg.db")
+	// Concurrent writers wait for the write lock rather than failing.
+	db, err := sql.Open("sqlite", "file:"+tmpPath+"?_pragma=busy_timeout(10000)")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open new database: %w", err)
 	}
 
-	if err = fleetsql.WritePackages(ctx, db, pkgs); err != nil {
+	if err = fleetsql.WritePackages(ctx, db, pkgs, writeOpts); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to write packages to DB: %w", err)
 	}
 	if err = db.Close(); err != nil {
 		return nil, fmt.Errorf("failed to close database: %w", err)
 	}
+	if err = os.Rename(tmpPath, dbPath); err != nil {
+		return nil, fmt.Errorf("failed to move database into place: %w", err)
+	}
 
 	// Open the database as read-only.
-	db, err = sql.Open("sqlite", "file:fleetpkg.db?mode=ro")
+	db, err = sql.Open("sqlite", "file:"+dbPath+"?mode=ro")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database readonly: %w", err)
 	}
+	setConnPool(db, opts.maxConns)
+
+	// WAL allows readers to proceed concurrently without SQLITE_BUSY errors.
+	// It cannot always be enabled on a read-only connection so failure is
+	// not fatal.
+	var mode string
+	if err = db.QueryRowContext(ctx, "PRAGMA journal_mode=WAL").Scan(&mode); err != nil {
+		log.Warn("Failed to enable WAL journal mode", slog.Any("error", err))
+	} else if mode != "wal" {
+		log.Debug("WAL journal mode not enabled", slog.String("journal_mode", mode))
+	}
 
 	return db, nil
 }
 
-// loadPackages loads integration packages from the specified directory.
-// It returns a slice of Integration structs or an error if loading fails.
-func loadPackages(log *slog.Logger, integrationsDir string) ([]fleetpkg.Integration, error) {
-	packages, err := filepath.Glob(filepath.Join(integrationsDir, "packages/*"))
-	if err != nil {
-		return nil, err
+// setConnPool limits the connection pool of db to maxConns open connections,
+// keeping up to half of them idle. A maxConns of zero or less leaves the
+// pool unlimited.
+func setConnPool(db *sql.DB, maxConns int) {
+	if maxConns <= 0 {
+		return
 	}
-	if len(packages) == 0 {
-		return nil, fmt.Errorf("no packages found in %s", integrationsDir)
+	db.SetMaxOpenConns(maxConns)
+	db.SetMaxIdleConns(max(1, maxConns/2))
+}
+
+// loadPackages loads integration packages from the packages directory of
+// each of integrationsDirs and from opts.packagePaths. integrationsDirs may
+// be empty when only individual packages are loaded. When integrationsDirs
+// contain more than one package with the same directory name only the first
+// one is loaded and the others are logged as warnings. A package in
+// opts.packagePaths replaces the package with the same directory name from
+// integrationsDirs. Packages are read concurrently by a bounded pool of
+// workers, but the returned slice preserves the order of the package
+// directories.
+// It returns a slice of Integration structs or an error if loading fails.
+// If opts.continueOnError is set then packages that fail to load are logged
+// and skipped.
+func loadPackages(log *slog.Logger, integrationsDirs []string, opts loadOptions) ([]fleetpkg.Integration, error) {
+	// Package directory names must be unique in the database.
+	overrides := map[string]string{}
+	for _, pkgPath := range opts.packagePaths {
+		overrides[filepath.Base(pkgPath)] = pkgPath
 	}
+	seen := map[string]string{}
 
-	var integrations []fleetpkg.Integration
-	for _, pkgPath := range packages {
-		p, err := fleetpkg.Read(pkgPath)
+	var packages []string
+	for _, dir := range integrationsDirs {
+		matches, err := filepath.Glob(filepath.Join(dir, "packages/*"))
 		if err != nil {
 			return nil, err
 		}
-		integrations = append(integrations, *p)
+		if len(matches) == 0 && len(opts.packagePaths) == 0 {
+			return nil, fmt.Errorf("no packages found in %s", dir)
+		}
+		for _, pkgPath := range matches {
+			name := filepath.Base(pkgPath)
+			if override, found := overrides[name]; found {
+				log.Debug("Using package from -pkg instead of integrations directory",
+					slog.String("package", name),
+					slog.String("path", override),
+					slog.String("replaced_path", pkgPath))
+				continue
+			}
+			if first, found := seen[name]; found {
+				log.Warn("Skipping duplicate package",
+					slog.String("package", name),
+					slog.String("path", pkgPath),
+					slog.String("loaded_path", first))
+				continue
+			}
+			seen[name] = pkgPath
+			packages = append(packages, pkgPath)
+		}
+	}
+	packages = append(packages, opts.packagePaths...)
+	if len(packages) == 0 {
+		return nil, errors.New("no packages to load")
+	}
+
+	if opts.packageFilter != "" {
+		if _, err := filepath.Match(opts.packageFilter, ""); err != nil {
+			return nil, fmt.Errorf("invalid package filter %q: %w", opts.packageFilter, err)
+		}
+		packages = slices.DeleteFunc(packages, func(pkgPath string) bool {
+			match, _ := filepath.Match(opts.packageFilter, filepath.Base(pkgPath))
+			return !match
+		})
+		if len(packages) == 0 {
+			return nil, fmt.Errorf("no packages in %s match filter %q", strings.Join(integrationsDirs, ", "), opts.packageFilter)
+		}
+	}
+
+	if len(opts.skipPackages) > 0 {
+		packages = slices.DeleteFunc(packages, func(pkgPath string) bool {
+			if slices.Contains(opts.skipPackages, filepath.Base(pkgPath)) {
+				log.Debug("Skipping excluded package", slog.String("package", filepath.Base(pkgPath)))
+				return true
+			}
+			return false
+		})
+	}
+
+	type result struct {
+		index int
+		pkg   *fleetpkg.Integration
+		err   error
+	}
+
+	jobs := make(chan int)
+	results := make(chan result, len(packages))
+
+	var wg sync.WaitGroup
+	for range min(runtime.NumCPU(), len(packages)) {
+		wg.Go(func() {
+			for i := range jobs {
+				p, err := fleetpkg.Read(packages[i])
+				results <- result{index: i, pkg: p, err: err}
+			}
+		})
+	}
+	for i := range packages {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	close(results)
+
+	// Write to index positions to keep the order deterministic and report
+	// the error from the first failing package.
+	loaded := make([]*fleetpkg.Integration, len(packages))
+	errIndex := -1
+	var err error
+	for r := range results {
+		if r.err != nil {
+			if opts.continueOnError {
+				log.Warn("Skipping package that failed to load",
+					slog.String("package", filepath.Base(packages[r.index])),
+					slog.Any("error", r.err))
+				continue
+			}
+			if errIndex < 0 || r.index < errIndex {
+				errIndex, err = r.index, r.err
+			}
+			continue
+		}
+		loaded[r.index] = r.pkg
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	integrations := make([]fleetpkg.Integration, 0, len(loaded))
+	for _, p := range loaded {
+		if p != nil {
+			integrations = append(integrations, *p)
+		}
+	}
+
+	attrs := []any{slog.Int("count", len(integrations))}
+	if skipped := len(packages) - len(integrations); skipped > 0 {
+		attrs = append(attrs, slog.Int("skipped_packages", skipped))
 	}
-	log.Info("Discovered packages", slog.Int("count", len(integrations)))
+	log.Info("Discovered packages", attrs...)
 
 	return integrations, nil
 }
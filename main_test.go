@@ -0,0 +1,443 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andrewkroh/go-fleetpkg"
+)
+
+// fixtureDir is a minimal elastic/integrations style directory.
+const fixtureDir = "testdata/integrations"
+
+func TestInitializeDatabaseDBPath(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "custom.db")
+
+	db, err := initializeDatabase(t.Context(), slog.New(slog.DiscardHandler), []string{fixtureDir}, dbOptions{path: dbPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err = os.Stat(dbPath); err != nil {
+		t.Fatalf("expected database at %s: %v", dbPath, err)
+	}
+
+	var count int
+	if err = db.QueryRowContext(t.Context(), `SELECT count(*) FROM integrations`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 integrations, got %d", count)
+	}
+}
+
+func TestInitializeDatabaseWorkers(t *testing.T) {
+	for _, inMemory := range []bool{false, true} {
+		t.Run(fmt.Sprintf("in_memory=%v", inMemory), func(t *testing.T) {
+			db, err := initializeDatabase(t.Context(), slog.New(slog.DiscardHandler), []string{fixtureDir}, dbOptions{
+				path:     filepath.Join(t.TempDir(), "fleetpkg.db"),
+				inMemory: inMemory,
+				workers:  3,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			var count int
+			if err = db.QueryRowContext(t.Context(), `SELECT count(*) FROM integrations`).Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			if count != 3 {
+				t.Fatalf("expected 3 integrations, got %d", count)
+			}
+		})
+	}
+}
+
+func TestInitializeDatabaseReadOnly(t *testing.T) {
+	for _, inMemory := range []bool{false, true} {
+		t.Run(fmt.Sprintf("in_memory=%v", inMemory), func(t *testing.T) {
+			db, err := initializeDatabase(t.Context(), slog.New(slog.DiscardHandler), []string{fixtureDir}, dbOptions{
+				path:     filepath.Join(t.TempDir(), "fleetpkg.db"),
+				inMemory: inMemory,
+				maxConns: 4,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			// Hold connections open so that the statement runs on a new one.
+			for range 2 {
+				conn, err := db.Conn(t.Context())
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer conn.Close()
+			}
+
+			if _, err = db.ExecContext(t.Context(), `WITH x AS (SELECT 1) DELETE FROM integrations`); err == nil {
+				t.Fatal("expected DELETE to fail on the read-only database")
+			}
+
+			var count int
+			if err = db.QueryRowContext(t.Context(), `SELECT count(*) FROM integrations`).Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			if count != 3 {
+				t.Fatalf("expected 3 integrations, got %d", count)
+			}
+		})
+	}
+}
+
+func TestInitializeDatabaseJournalMode(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "fleetpkg.db")
+
+	// Enabling WAL is best-effort and does not prevent the database from
+	// being served.
+	db, err := initializeDatabase(t.Context(), slog.New(slog.DiscardHandler), []string{fixtureDir}, dbOptions{path: dbPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var count int
+	if err = db.QueryRowContext(t.Context(), `SELECT count(*) FROM integrations`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+
+	// A reload renames a new file over one that is still open, so the
+	// database must not use path-based WAL sidecar files.
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if _, err = os.Stat(dbPath + suffix); !os.IsNotExist(err) {
+			t.Errorf("expected no %s file, got %v", suffix, err)
+		}
+	}
+}
+
+func TestLoadPackagesOrder(t *testing.T) {
+	pkgs, err := loadPackages(slog.New(slog.DiscardHandler), []string{fixtureDir}, loadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"aws_cloudtrail", "aws_s3", "nginx"}
+	if len(pkgs) != len(want) {
+		t.Fatalf("expected %d packages, got %d", len(want), len(pkgs))
+	}
+	for i, name := range want {
+		if got := pkgs[i].Manifest.Name; got != name {
+			t.Errorf("package %d: expected %s, got %s", i, name, got)
+		}
+	}
+}
+
+func TestLoadPackagesContinueOnError(t *testing.T) {
+	// Copy the fixture and add a package with a malformed manifest.
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS(fixtureDir)); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "packages", "broken")
+	if err := os.MkdirAll(broken, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(broken, "manifest.yml"), []byte("name: [unterminated"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	log := slog.New(slog.DiscardHandler)
+
+	if _, err := loadPackages(log, []string{dir}, loadOptions{}); err == nil {
+		t.Fatal("expected an error without continueOnError")
+	}
+
+	pkgs, err := loadPackages(log, []string{dir}, loadOptions{continueOnError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 3 {
+		t.Fatalf("expected 3 packages, got %d", len(pkgs))
+	}
+}
+
+func TestLoadPackagesPackagePaths(t *testing.T) {
+	log := slog.New(slog.DiscardHandler)
+	nginx := filepath.Join(fixtureDir, "packages", "nginx")
+
+	pkgs, err := loadPackages(log, nil, loadOptions{packagePaths: []string{nginx}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 1 || pkgs[0].Manifest.Name != "nginx" {
+		t.Fatalf("expected only the nginx package, got %d packages", len(pkgs))
+	}
+
+	db, err := initializeDatabase(t.Context(), log, nil, dbOptions{
+		path: filepath.Join(t.TempDir(), "fleetpkg.db"),
+		load: loadOptions{packagePaths: []string{nginx}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var name string
+	if err = db.QueryRowContext(t.Context(), `SELECT name FROM integrations`).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "nginx" {
+		t.Fatalf("expected nginx, got %s", name)
+	}
+
+	if _, err = loadPackages(log, nil, loadOptions{}); err == nil {
+		t.Fatal("expected an error when there are no packages to load")
+	}
+}
+
+func TestLoadPackagesMultipleDirs(t *testing.T) {
+	// Split the fixture across two directories that both contain nginx.
+	public, private := t.TempDir(), t.TempDir()
+	for dir, names := range map[string][]string{
+		public:  {"aws_s3", "nginx"},
+		private: {"aws_cloudtrail", "nginx"},
+	} {
+		for _, name := range names {
+			src := os.DirFS(filepath.Join(fixtureDir, "packages", name))
+			if err := os.CopyFS(filepath.Join(dir, "packages", name), src); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var logBuf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&logBuf, nil))
+
+	pkgs, err := loadPackages(log, []string{public, private}, loadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"aws_s3", "nginx", "aws_cloudtrail"}
+	if len(pkgs) != len(want) {
+		t.Fatalf("expected %d packages, got %d", len(want), len(pkgs))
+	}
+	for i, name := range want {
+		if got := pkgs[i].Manifest.Name; got != name {
+			t.Errorf("package %d: expected %s, got %s", i, name, got)
+		}
+	}
+
+	// The nginx package from the first directory wins.
+	if !strings.HasPrefix(pkgs[1].Path(), public) {
+		t.Errorf("expected nginx from %s, got %s", public, pkgs[1].Path())
+	}
+	if !strings.Contains(logBuf.String(), "Skipping duplicate package") {
+		t.Errorf("expected a duplicate package warning, got %q", logBuf.String())
+	}
+}
+
+func TestLoadPackagesPackagePathOverridesDir(t *testing.T) {
+	// A working copy of nginx outside of the integrations directory.
+	nginx := filepath.Join(t.TempDir(), "nginx")
+	if err := os.CopyFS(nginx, os.DirFS(filepath.Join(fixtureDir, "packages", "nginx"))); err != nil {
+		t.Fatal(err)
+	}
+
+	var logBuf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&logBuf, nil))
+
+	pkgs, err := loadPackages(log, []string{fixtureDir}, loadOptions{packagePaths: []string{nginx}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"aws_cloudtrail", "aws_s3", "nginx"}
+	if len(pkgs) != len(want) {
+		t.Fatalf("expected %d packages, got %d", len(want), len(pkgs))
+	}
+	for i, name := range want {
+		if got := pkgs[i].Manifest.Name; got != name {
+			t.Errorf("package %d: expected %s, got %s", i, name, got)
+		}
+	}
+	if got := pkgs[2].Path(); got != nginx {
+		t.Errorf("expected nginx from %s, got %s", nginx, got)
+	}
+	if strings.Contains(logBuf.String(), "Skipping duplicate package") {
+		t.Errorf("unexpected duplicate package warning: %q", logBuf.String())
+	}
+}
+
+func TestSkipPackage(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "fleetpkg.db")
+
+	db, err := initializeDatabase(t.Context(), slog.New(slog.DiscardHandler), []string{fixtureDir}, dbOptions{
+		path: dbPath,
+		load: loadOptions{skipPackages: []string{"nginx"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var count int
+	if err = db.QueryRowContext(t.Context(), `SELECT count(*) FROM integrations WHERE name = 'nginx'`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatal("skipped package nginx was written to the database")
+	}
+	if err = db.QueryRowContext(t.Context(), `SELECT count(*) FROM integrations`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 integrations, got %d", count)
+	}
+}
+
+func TestPackageFilter(t *testing.T) {
+	log := slog.New(slog.DiscardHandler)
+
+	pkgs, err := loadPackages(log, []string{fixtureDir}, loadOptions{packageFilter: "aws_*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 2 {
+		t.Fatalf("expected 2 packages, got %d", len(pkgs))
+	}
+	for _, p := range pkgs {
+		if !strings.HasPrefix(p.Manifest.Name, "aws_") {
+			t.Errorf("unexpected package %s", p.Manifest.Name)
+		}
+	}
+
+	if _, err = loadPackages(log, []string{fixtureDir}, loadOptions{packageFilter: "gcp_*"}); err == nil {
+		t.Fatal("expected an error when the filter matches nothing")
+	}
+}
+
+func BenchmarkLoadPackages(b *testing.B) {
+	integrationsDir := os.Getenv("INTEGRATIONS_DIR")
+	if integrationsDir == "" {
+		b.Skip("INTEGRATIONS_DIR env var is not set.")
+	}
+	log := slog.New(slog.DiscardHandler)
+
+	b.Run("serial", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := loadPackagesSerial(integrationsDir); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := loadPackages(log, []string{integrationsDir}, loadOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// loadPackagesSerial is the serial baseline for BenchmarkLoadPackages.
+func loadPackagesSerial(integrationsDir string) ([]fleetpkg.Integration, error) {
+	packages, err := filepath.Glob(filepath.Join(integrationsDir, "packages/*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var integrations []fleetpkg.Integration
+	for _, pkgPath := range packages {
+		p, err := fleetpkg.Read(pkgPath)
+		if err != nil {
+			return nil, err
+		}
+		integrations = append(integrations, *p)
+	}
+	return integrations, nil
+}
+
+func TestLoggerJSONFormat(t *testing.T) {
+	defer func(format, level string) { *logFormat, *logLevel = format, level }(*logFormat, *logLevel)
+	*logFormat, *logLevel = "json", "warn"
+
+	var buf bytes.Buffer
+	log, err := logger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Info("filtered by level")
+	log.Warn("disk almost full")
+
+	var entry map[string]any
+	if err = json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a single JSON log line, got %q: %v", buf.String(), err)
+	}
+	if entry["level"] != "WARN" {
+		t.Errorf("expected level WARN, got %v", entry["level"])
+	}
+	if entry["msg"] != "disk almost full" {
+		t.Errorf("expected msg 'disk almost full', got %v", entry["msg"])
+	}
+}
+
+func TestInitializeDatabaseConcurrentQueries(t *testing.T) {
+	db, err := initializeDatabase(t.Context(), slog.New(slog.DiscardHandler), []string{fixtureDir}, dbOptions{
+		path:     filepath.Join(t.TempDir(), "fleetpkg.db"),
+		maxConns: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if got := db.Stats().MaxOpenConnections; got != 10 {
+		t.Fatalf("expected 10 max open connections, got %d", got)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for range 20 {
+		wg.Go(func() {
+			var count int
+			if err := db.QueryRowContext(t.Context(), `SELECT count(*) FROM integrations`).Scan(&count); err != nil {
+				errs <- err
+				return
+			}
+			if count != 3 {
+				errs <- fmt.Errorf("expected 3 integrations, got %d", count)
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestBuildVersion(t *testing.T) {
+	v := buildVersion()
+	if v.GoVersion == "" {
+		t.Error("expected a Go toolchain version")
+	}
+	if v.String() == "" {
+		t.Error("expected a non-empty version string")
+	}
+}
@@ -0,0 +1,68 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// tsvEscaper replaces characters that would break the tab-separated layout.
+var tsvEscaper = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+
+// runQuery builds the database, executes a single SQL statement, and writes
+// the result to w as tab-separated values with a header row.
+func runQuery(ctx context.Context, log *slog.Logger, w io.Writer, integrationsDirs []string, opts dbOptions, statement string) error {
+	db, err := initializeDatabase(ctx, log, integrationsDirs, opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, statement)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, strings.Join(columns, "\t"))
+
+	values := make([]any, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	fields := make([]string, len(columns))
+	for rows.Next() {
+		if err = rows.Scan(ptrs...); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		for i, v := range values {
+			switch v := v.(type) {
+			case nil:
+				fields[i] = ""
+			case []byte:
+				fields[i] = tsvEscaper.Replace(string(v))
+			default:
+				fields[i] = tsvEscaper.Replace(fmt.Sprint(v))
+			}
+		}
+		fmt.Fprintln(bw, strings.Join(fields, "\t"))
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,35 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestRunQuery(t *testing.T) {
+	var buf bytes.Buffer
+	err := runQuery(t.Context(), slog.New(slog.DiscardHandler), &buf, []string{fixtureDir}, dbOptions{inMemory: true},
+		`SELECT name, 'a'||char(9)||'b' AS tabbed, NULL AS empty FROM integrations ORDER BY name LIMIT 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "name\ttabbed\tempty\n" +
+		"aws_cloudtrail\ta b\t\n" +
+		"aws_s3\ta b\t\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected output:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestRunQueryInvalidStatement(t *testing.T) {
+	var buf bytes.Buffer
+	err := runQuery(t.Context(), slog.New(slog.DiscardHandler), &buf, []string{fixtureDir}, dbOptions{inMemory: true}, `SELECT * FROM no_such_table`)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
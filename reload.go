@@ -0,0 +1,80 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// databaseLoader builds the database and publishes it to the pointer that is
+// shared with the MCP tools.
+type databaseLoader struct {
+	log              *slog.Logger
+	integrationsDirs []string
+	opts             dbOptions
+	db               *atomic.Pointer[sql.DB]
+	tracer           trace.Tracer  // Optional. When set, each build is traced.
+	closeDelay       time.Duration // How long the previous database stays open after a reload.
+
+	mu sync.Mutex // Serializes builds because they share the same file path.
+}
+
+// build creates a new database without publishing it.
+func (l *databaseLoader) build(ctx context.Context) (*sql.DB, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.tracer != nil {
+		var span trace.Span
+		ctx, span = l.tracer.Start(ctx, "initializeDatabase")
+		defer span.End()
+
+		db, err := initializeDatabase(ctx, l.log, l.integrationsDirs, l.opts)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return db, err
+	}
+
+	return initializeDatabase(ctx, l.log, l.integrationsDirs, l.opts)
+}
+
+// reloadCloseDelay is how long the previous database stays open after a
+// reload. Tool calls that loaded it just before the swap can still start
+// their queries; queries that have already started are waited for by Close.
+const reloadCloseDelay = 10 * time.Second
+
+// reload rebuilds the database and atomically swaps it in place of the
+// current one, which is closed after closeDelay. If the rebuild fails the
+// current database continues to be served.
+func (l *databaseLoader) reload(ctx context.Context) error {
+	start := time.Now()
+	l.log.Info("Starting database reload...")
+
+	db, err := l.build(ctx)
+	if err != nil {
+		l.log.Error("Database reload failed, continuing with the existing database", slog.Any("error", err))
+		return err
+	}
+
+	if old := l.db.Swap(db); old != nil {
+		time.AfterFunc(l.closeDelay, func() {
+			if err := old.Close(); err != nil {
+				l.log.Warn("Failed to close previous database", slog.Any("error", err))
+			}
+		})
+	}
+	l.log.Info("Database reload completed", slog.Duration("duration", time.Since(start)))
+	return nil
+}
@@ -0,0 +1,119 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestDatabaseLoaderReload(t *testing.T) {
+	dbPtr := &atomic.Pointer[sql.DB]{}
+	loader := &databaseLoader{
+		log:              slog.New(slog.DiscardHandler),
+		integrationsDirs: []string{fixtureDir},
+		opts:             dbOptions{path: filepath.Join(t.TempDir(), "fleetpkg.db")},
+		db:               dbPtr,
+		tracer:           noop.NewTracerProvider().Tracer("test"),
+		closeDelay:       200 * time.Millisecond,
+	}
+
+	db, err := loader.build(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbPtr.Store(db)
+
+	if err = loader.reload(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	newDB := dbPtr.Load()
+	defer newDB.Close()
+
+	if newDB == db {
+		t.Fatal("expected the database pointer to change after reload")
+	}
+
+	// Tool calls that loaded the previous database before the swap can
+	// still query it until the close delay has passed.
+	var count int
+	if err = db.QueryRowContext(t.Context(), `SELECT count(*) FROM integrations`).Scan(&count); err != nil {
+		t.Fatalf("expected the previous database to remain open: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for db.Ping() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the previous database to be closed after the delay")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if err = newDB.QueryRowContext(t.Context(), `SELECT count(*) FROM integrations`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 integrations, got %d", count)
+	}
+}
+
+func TestDatabaseLoaderWatch(t *testing.T) {
+	integrationsDir := t.TempDir()
+	if err := os.CopyFS(integrationsDir, os.DirFS(fixtureDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	dbPtr := &atomic.Pointer[sql.DB]{}
+	loader := &databaseLoader{
+		log:              slog.New(slog.DiscardHandler),
+		integrationsDirs: []string{integrationsDir},
+		opts:             dbOptions{path: filepath.Join(t.TempDir(), "fleetpkg.db")},
+		db:               dbPtr,
+	}
+
+	db, err := loader.build(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbPtr.Store(db)
+	t.Cleanup(func() { dbPtr.Load().Close() })
+
+	ctx, cancel := context.WithCancel(t.Context())
+	watchDone := make(chan error, 1)
+	go func() { watchDone <- loader.watch(ctx, 100*time.Millisecond) }()
+	defer func() {
+		cancel()
+		if err := <-watchDone; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// Give the watcher time to register its directories.
+	time.Sleep(100 * time.Millisecond)
+
+	manifest := filepath.Join(integrationsDir, "packages/nginx/manifest.yml")
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(manifest, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for dbPtr.Load() == db {
+		if time.Now().After(deadline) {
+			t.Fatal("database was not reloaded after the manifest changed")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
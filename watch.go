@@ -0,0 +1,94 @@
+// Licensed to Elasticsearch B.V. under one or more agreements.
+// Elasticsearch B.V. licenses this file to you under the Apache 2.0 License.
+// See the LICENSE file in the project root for more information.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the watcher waits after the last change before
+// reloading the database.
+const watchDebounce = 2 * time.Second
+
+// watch monitors the packages directory of each integrations tree and any
+// individually loaded package directories, and reloads the database once no
+// write or create events have been observed for the debounce period. It
+// blocks until ctx is done.
+func (l *databaseLoader) watch(ctx context.Context, debounce time.Duration) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	var dirs []string
+	for _, dir := range l.integrationsDirs {
+		dirs = append(dirs, filepath.Join(dir, "packages"))
+	}
+	dirs = append(dirs, l.opts.load.packagePaths...)
+	for _, dir := range dirs {
+		if err = addWatchDirs(w, dir); err != nil {
+			return err
+		}
+		l.log.Info("Watching for package changes", slog.String("dir", dir))
+	}
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	var trigger string
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-w.Errors:
+			l.log.Warn("File watcher error", slog.Any("error", err))
+		case ev := <-w.Events:
+			if !ev.Has(fsnotify.Write) && !ev.Has(fsnotify.Create) {
+				continue
+			}
+			// Watch directories created after startup (e.g. new packages).
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					if err = addWatchDirs(w, ev.Name); err != nil {
+						l.log.Warn("Failed to watch new directory", slog.String("path", ev.Name), slog.Any("error", err))
+					}
+				}
+			}
+			l.log.Debug("Package file changed", slog.String("path", ev.Name), slog.String("op", ev.Op.String()))
+			trigger = ev.Name
+			timer.Reset(debounce)
+		case <-timer.C:
+			l.log.Info("Package change detected, reloading database", slog.String("path", trigger))
+			_ = l.reload(ctx)
+		}
+	}
+}
+
+// addWatchDirs adds root and all directories beneath it to the watcher
+// because fsnotify does not watch recursively.
+func addWatchDirs(w *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return w.Add(path)
+	})
+}